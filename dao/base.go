@@ -9,15 +9,9 @@ import (
 	"github.com/pundiai/go-sdk/db"
 )
 
-type (
-	ctxKeyTx    struct{}
-	ctxKeyHasTx struct{}
-)
+type ctxKeyHasTx struct{}
 
-var (
-	keyTx    = ctxKeyTx{}
-	keyHasTx = ctxKeyHasTx{}
-)
+var keyHasTx = ctxKeyHasTx{}
 
 type Model interface {
 	schema.Tabler
@@ -45,6 +39,12 @@ func (d *BaseDao) Count(funcs ...func(db db.DB) db.DB) (count int64) {
 	return count
 }
 
+// Paginate finds one page of dest by keyset, funcs filter the rows like Count
+// and must not order them, the order comes from req
+func (d *BaseDao) Paginate(dest any, req db.PageRequest, funcs ...func(db db.DB) db.DB) (db.Page, error) {
+	return d.db.Model(d.model).Scopes(funcs...).Paginate(dest, req)
+}
+
 func (d *BaseDao) UpdatesByID(id uint, data Model) error {
 	err := d.db.Model(d.model).
 		Where("id = ?", id).
@@ -67,6 +67,30 @@ func (d *BaseDao) DeleteByID(id uint) error {
 	return nil
 }
 
+// RestoreByID clears the deleted-at column of a soft deleted model
+func (d *BaseDao) RestoreByID(id uint) error {
+	err := d.db.Unscoped().Model(d.model).
+		Where("id = ? AND deleted_at IS NOT NULL", id).
+		RowsAffected(1).
+		Update("deleted_at", nil)
+	if err != nil {
+		return errors.WithMessagef(err, "id: %d", id)
+	}
+	return nil
+}
+
+// HardDeleteByID deletes the model permanently, even when it embeds model.SoftDeleteBase
+func (d *BaseDao) HardDeleteByID(id uint) error {
+	err := d.db.Unscoped().Model(d.model).
+		Where("id = ?", id).
+		RowsAffected(1).
+		Delete(nil)
+	if err != nil {
+		return errors.WithMessagef(err, "id: %d", id)
+	}
+	return nil
+}
+
 func (d *BaseDao) GetByID(id uint, result Model) (bool, error) {
 	found, err := d.db.Model(d.model).
 		Where("id = ?", id).
@@ -77,18 +101,21 @@ func (d *BaseDao) GetByID(id uint, result Model) (bool, error) {
 	return found, nil
 }
 
+// Transaction runs fn in the transaction carried by ctx, nested on a savepoint,
+// or in a new one, see db.Transaction
 func (d *BaseDao) Transaction(ctx context.Context, fn func(ctx context.Context) error) error {
-	return d.UnwrapContextDBOrDefault(ctx).Transaction(func(tx db.DB) error {
-		return fn(context.WithValue(ctx, keyTx, tx))
-	})
+	return db.Transaction(ctx, d.db, fn)
 }
 
+// BeginTx starts a transaction carried by the returned ctx, it joins the
+// transaction already carried by ctx, whose commit and rollback are then left
+// to the one who began it.
 func (d *BaseDao) BeginTx(ctx context.Context) context.Context {
-	if _, ok := ctx.Value(keyTx).(db.DB); ok {
+	if db.FromContext(ctx, nil) != nil {
 		ctx = context.WithValue(ctx, keyHasTx, true)
 		return ctx
 	}
-	return context.WithValue(ctx, keyTx, d.db.Begin())
+	return db.WithTx(ctx, d.db.Begin())
 }
 
 func (*BaseDao) CommitTx(ctx context.Context) error {
@@ -108,8 +135,8 @@ func (*BaseDao) RollbackTx(ctx context.Context) error {
 }
 
 func hasOperatorTx(ctx context.Context) (db.DB, bool) {
-	tx, ok := ctx.Value(keyTx).(db.DB)
-	if !ok {
+	tx := db.FromContext(ctx, nil)
+	if tx == nil {
 		return nil, false
 	}
 	if hasTx, ok := ctx.Value(keyHasTx).(bool); ok && hasTx {
@@ -119,11 +146,7 @@ func hasOperatorTx(ctx context.Context) (db.DB, bool) {
 }
 
 func (d *BaseDao) UnwrapContextDBOrDefault(ctx context.Context) db.DB {
-	value, ok := ctx.Value(keyTx).(db.DB)
-	if ok {
-		return value
-	}
-	return d.db
+	return db.FromContext(ctx, d.db)
 }
 
 func (d *BaseDao) InsertWithCtx(ctx context.Context, model Model) error {
@@ -31,6 +31,27 @@ func NewTestModel(name string, number uint64) *TestModel {
 	}
 }
 
+type SoftDeleteModel struct {
+	model.SoftDeleteBase `gorm:"embedded"`
+
+	Name string `gorm:"column:name; type:varchar(20); not null"`
+}
+
+func (*SoftDeleteModel) TableName() string {
+	return "soft_delete_model"
+}
+
+type VersionedModel struct {
+	model.Base      `gorm:"embedded"`
+	model.Versioned `gorm:"embedded"`
+
+	Name string `gorm:"column:name; type:varchar(20); not null"`
+}
+
+func (*VersionedModel) TableName() string {
+	return "versioned_model"
+}
+
 type DaoTestSuite struct {
 	suite.Suite
 	baseDao *dao.BaseDao
@@ -94,6 +115,111 @@ func (s *DaoTestSuite) TestUpdatesByID() {
 	s.Require().EqualValues(updateData, actualData)
 }
 
+func (s *DaoTestSuite) TestDeleteByIDNotFound() {
+	err := s.baseDao.DeleteByID(100)
+	s.Require().ErrorIs(err, db.ErrRowsAffectedMismatch)
+	s.Require().EqualError(err, "id: 100: db delete error, rows affected: 0, expected: 1")
+
+	s.Require().NoError(s.baseDao.Insert(NewTestModel("test", 100)))
+	s.Require().ErrorIs(s.baseDao.Insert(NewTestModel("test", 200)), db.ErrDuplicateKey)
+}
+
+func (s *DaoTestSuite) TestPaginate() {
+	for i := 1; i <= 5; i++ {
+		s.Require().NoError(s.baseDao.Insert(NewTestModel(fmt.Sprintf("test%d", i), uint64(i))))
+	}
+	var testModels []TestModel
+	page, err := s.baseDao.Paginate(&testModels, db.PageRequest{Limit: 2, OrderBy: "id desc"})
+	s.Require().NoError(err)
+	s.Require().Len(testModels, 2)
+	s.Equal("test5", testModels[0].Name)
+
+	_, err = s.baseDao.Paginate(&testModels, db.PageRequest{After: page.Next, Limit: 2, OrderBy: "id desc"})
+	s.Require().NoError(err)
+	s.Require().Len(testModels, 2)
+	s.Equal("test3", testModels[0].Name)
+
+	_, err = s.baseDao.Paginate(&testModels, db.PageRequest{Limit: 2}, db.OrderByDesc("name"))
+	s.Require().EqualError(err, "db paginate error: query is already ordered, order by the page request instead")
+}
+
+func (s *DaoTestSuite) TestCount() {
+	for i := 1; i <= 5; i++ {
+		s.Require().NoError(s.baseDao.Insert(NewTestModel(fmt.Sprintf("test%d", i), uint64(i))))
+	}
+	s.EqualValues(5, s.baseDao.Count())
+	s.EqualValues(2, s.baseDao.Count(db.ByIDs(1, 2)))
+	s.EqualValues(1, s.baseDao.Count(db.ByIDs(1, 2), db.WhereIn("name", []string{"test2", "test3"})))
+}
+
+func (s *DaoTestSuite) TestSoftDelete() {
+	softDao := dao.NewDao(s.baseDao.GetDB(), &SoftDeleteModel{})
+	s.Require().NoError(s.baseDao.GetDB().AutoMigrate(new(SoftDeleteModel)))
+	s.Require().NoError(softDao.Insert(&SoftDeleteModel{Name: "test1"}))
+	s.Require().NoError(softDao.Insert(&SoftDeleteModel{Name: "test2"}))
+
+	s.Require().NoError(softDao.DeleteByID(1))
+	found, err := softDao.GetByID(1, &SoftDeleteModel{})
+	s.Require().NoError(err)
+	s.False(found)
+	s.EqualValues(1, softDao.Count())
+	deleted := &SoftDeleteModel{}
+	found, err = softDao.GetDB().Unscoped().Where("id = ?", 1).First(deleted)
+	s.Require().NoError(err)
+	s.True(found)
+	s.True(deleted.IsDeleted())
+
+	s.Require().NoError(softDao.RestoreByID(1))
+	s.Require().ErrorIs(softDao.RestoreByID(2), db.ErrRowsAffectedMismatch)
+	found, err = softDao.GetByID(1, &SoftDeleteModel{})
+	s.Require().NoError(err)
+	s.True(found)
+
+	s.Require().NoError(softDao.HardDeleteByID(1))
+	found, err = softDao.GetDB().Unscoped().Where("id = ?", 1).First(&SoftDeleteModel{})
+	s.Require().NoError(err)
+	s.False(found)
+	s.Require().ErrorIs(softDao.HardDeleteByID(1), db.ErrRowsAffectedMismatch)
+}
+
+func (s *DaoTestSuite) TestUpdatesByIDStaleVersion() {
+	versionedDao := dao.NewDao(s.baseDao.GetDB(), &VersionedModel{})
+	s.Require().NoError(s.baseDao.GetDB().AutoMigrate(new(VersionedModel)))
+	s.Require().NoError(versionedDao.Insert(&VersionedModel{Name: "test"}))
+
+	data := &VersionedModel{}
+	_, err := versionedDao.GetByID(1, data)
+	s.Require().NoError(err)
+	stale := *data
+
+	data.Name = "test1"
+	s.Require().NoError(versionedDao.UpdatesByID(1, data))
+	s.EqualValues(2, data.Version)
+
+	stale.Name = "test2"
+	err = versionedDao.UpdatesByID(1, &stale)
+	s.Require().ErrorIs(err, db.ErrStaleVersion)
+	s.Require().EqualError(err, "id: 1: db updates error: stale version")
+}
+
+func (s *DaoTestSuite) TestRecorderDB() {
+	recorderDB, recorder := db.NewRecorderDB(db.SqliteDriver)
+	recorderDao := dao.NewDao(recorderDB, &TestModel{})
+
+	s.Require().NoError(recorderDao.UpdatesByID(1, NewTestModel("test", 100)))
+	statement := recorder.Last()
+	s.Equal("UPDATE `test_model` SET `updated_at`=?,`name`=?,`number`=? WHERE id = ?", statement.SQL)
+	s.Equal([]any{"test", uint64(100), uint(1)}, statement.Vars[1:])
+
+	recorder.SetResult("test_model", &TestModel{Base: model.Base{ID: 1}, Name: "test"})
+	result := &TestModel{}
+	found, err := recorderDao.GetByID(1, result)
+	s.Require().NoError(err)
+	s.True(found)
+	s.Equal("test", result.Name)
+	s.Equal("SELECT * FROM `test_model` WHERE id = 1 ORDER BY `test_model`.`id` LIMIT 1", recorder.Explain(recorder.Last()))
+}
+
 func (s *DaoTestSuite) TestNoTransaction() {
 	func() {
 		data := NewTestModel("test", 100)
@@ -271,6 +397,26 @@ func (s *DaoTestSuite) TestTransaction2WithCtx() {
 	}
 }
 
+func (s *DaoTestSuite) TestTransactionAcrossDaos() {
+	softDao := dao.NewDao(s.baseDao.GetDB(), &SoftDeleteModel{})
+	s.Require().NoError(s.baseDao.GetDB().AutoMigrate(new(SoftDeleteModel)))
+
+	s.Require().Error(db.Transaction(context.Background(), s.baseDao.GetDB(), func(ctx context.Context) error {
+		s.Require().NoError(s.baseDao.InsertWithCtx(ctx, NewTestModel("test1", 100)))
+		s.Require().NoError(softDao.InsertWithCtx(ctx, &SoftDeleteModel{Name: "test1"}))
+		return fmt.Errorf("rollback")
+	}))
+	s.Zero(s.baseDao.Count())
+	s.Zero(softDao.Count())
+
+	s.Require().NoError(db.Transaction(context.Background(), s.baseDao.GetDB(), func(ctx context.Context) error {
+		s.Require().NoError(s.baseDao.InsertWithCtx(ctx, NewTestModel("test1", 100)))
+		return softDao.InsertWithCtx(ctx, &SoftDeleteModel{Name: "test1"})
+	}))
+	s.EqualValues(1, s.baseDao.Count())
+	s.EqualValues(1, softDao.Count())
+}
+
 func (s *DaoTestSuite) TestCallCommitMultipleTimes() {
 	txCtx := s.baseDao.BeginTx(context.Background())
 	s.Require().NoError(s.baseDao.CommitTx(txCtx))
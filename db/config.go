@@ -2,9 +2,7 @@ package db
 
 import (
 	"encoding/json"
-	"fmt"
 	"os"
-	"strings"
 	"time"
 
 	"github.com/pkg/errors"
@@ -27,9 +25,57 @@ type Config struct {
 	MaxIdleConn     int           `yaml:"max_idle_conn" mapstructure:"max_idle_conn"`
 	MaxOpenConn     int           `yaml:"max_open_conn" mapstructure:"max_open_conn"`
 	LogLevel        string        `yaml:"log_level" mapstructure:"log_level"`
+	// SlowThreshold logs the sql slower than it as a warning, zero disables it
+	SlowThreshold time.Duration `yaml:"slow_threshold" mapstructure:"slow_threshold"`
+	// ParameterizedQueries leaves the bound variables out of the sql logs
+	ParameterizedQueries bool `yaml:"parameterized_queries" mapstructure:"parameterized_queries"`
 
 	EnableMetric          bool          `yaml:"enable_metric" mapstructure:"enable_metric"`
 	RefreshMetricInterval time.Duration `yaml:"refresh_metric_interval" mapstructure:"refresh_metric_interval"`
+	// EnableStatementMetric records the latency and errors of every statement through telemetry
+	EnableStatementMetric bool `yaml:"enable_statement_metric" mapstructure:"enable_statement_metric"`
+	// EnableTracing opens an OpenTelemetry span for every statement
+	EnableTracing bool `yaml:"enable_tracing" mapstructure:"enable_tracing"`
+
+	// Replicas are read-only sources, queries are load balanced across them
+	// while writes and transactions stay on Source
+	Replicas []string `yaml:"replicas" mapstructure:"replicas"`
+	// Resolvers route the listed tables to their own sources and replicas
+	Resolvers []ResolverConfig `yaml:"resolvers" mapstructure:"resolvers"`
+
+	// User and PasswordFile override the credentials of every source, which may
+	// also reference its password as file:///path. Password files are read again
+	// on every new connection, so a rotated password is picked up on reconnect.
+	User         string `yaml:"user" mapstructure:"user"`
+	PasswordFile string `yaml:"password_file" mapstructure:"password_file"`
+
+	// TxMaxRetries re-runs a transaction that failed with an error the driver
+	// classifies as retryable (deadlock, lock wait timeout, busy), zero disables it
+	TxMaxRetries      int           `yaml:"tx_max_retries" mapstructure:"tx_max_retries"`
+	TxRetryBackoff    time.Duration `yaml:"tx_retry_backoff" mapstructure:"tx_retry_backoff"`
+	TxRetryMaxBackoff time.Duration `yaml:"tx_retry_max_backoff" mapstructure:"tx_retry_max_backoff"`
+
+	// ConnectRetry keeps NewDB retrying the first connection, for a database
+	// starting along with the service
+	ConnectRetry ConnectRetryConfig `yaml:"connect_retry" mapstructure:"connect_retry"`
+
+	// Sqlite sets the pragmas of every sqlite connection, it is ignored by the other drivers
+	Sqlite SqliteConfig `yaml:"sqlite" mapstructure:"sqlite"`
+	// Mysql sets how CreateDB creates a mysql database, it is ignored by the other drivers
+	Mysql MysqlConfig `yaml:"mysql" mapstructure:"mysql"`
+}
+
+type ConnectRetryConfig struct {
+	// MaxWait bounds the time spent retrying, zero disables the retry
+	MaxWait    time.Duration `yaml:"max_wait" mapstructure:"max_wait"`
+	Backoff    time.Duration `yaml:"backoff" mapstructure:"backoff"`
+	MaxBackoff time.Duration `yaml:"max_backoff" mapstructure:"max_backoff"`
+}
+
+type ResolverConfig struct {
+	Tables   []string `yaml:"tables" mapstructure:"tables"`
+	Sources  []string `yaml:"sources" mapstructure:"sources"`
+	Replicas []string `yaml:"replicas" mapstructure:"replicas"`
 }
 
 func NewDefConfig() Config {
@@ -42,8 +88,37 @@ func NewDefConfig() Config {
 		MaxOpenConn:     30,
 		LogLevel:        LogLevelSilent,
 
+		SlowThreshold:        2 * time.Second,
+		ParameterizedQueries: false,
+
 		EnableMetric:          true,
 		RefreshMetricInterval: 15 * time.Second,
+		EnableStatementMetric: false,
+		EnableTracing:         false,
+
+		TxMaxRetries:      0,
+		TxRetryBackoff:    50 * time.Millisecond,
+		TxRetryMaxBackoff: time.Second,
+
+		ConnectRetry: ConnectRetryConfig{
+			MaxWait:    0,
+			Backoff:    500 * time.Millisecond,
+			MaxBackoff: 5 * time.Second,
+		},
+
+		Sqlite: SqliteConfig{
+			JournalMode: "",
+			BusyTimeout: 5 * time.Second,
+			Synchronous: "",
+			ForeignKeys: false,
+		},
+
+		Mysql: MysqlConfig{
+			Charset:   defaultMysqlCharset,
+			Collation: defaultMysqlCollation,
+			GrantUser: "",
+			GrantHost: "%",
+		},
 	}
 }
 
@@ -57,16 +132,27 @@ func (c Config) String() string {
 
 func (c Config) MarshalYAML() (any, error) {
 	type marshalConfig Config
-	temp := marshalConfig(c)
-	temp.Source = SourceDesensitization(temp.Source)
-	return temp, nil
+	return marshalConfig(c.desensitization()), nil
 }
 
 func (c Config) MarshalJSON() ([]byte, error) {
 	type marshalConfig Config
-	temp := marshalConfig(c)
-	temp.Source = SourceDesensitization(temp.Source)
-	return json.Marshal(temp)
+	return json.Marshal(marshalConfig(c.desensitization()))
+}
+
+func (c Config) desensitization() Config {
+	c.Source = c.sourceDesensitization(c.Source)
+	c.Replicas = c.sourcesDesensitization(c.Replicas)
+	resolvers := make([]ResolverConfig, 0, len(c.Resolvers))
+	for _, resolver := range c.Resolvers {
+		resolver.Sources = c.sourcesDesensitization(resolver.Sources)
+		resolver.Replicas = c.sourcesDesensitization(resolver.Replicas)
+		resolvers = append(resolvers, resolver)
+	}
+	if c.Resolvers != nil {
+		c.Resolvers = resolvers
+	}
+	return c
 }
 
 func (c Config) Validate() error { //nolint:revive // cyclomatic
@@ -77,7 +163,7 @@ func (c Config) Validate() error { //nolint:revive // cyclomatic
 	if err != nil {
 		return errors.WithMessage(err, "driver is invalid")
 	}
-	if err = driver.ParseSource(c.Source); err != nil {
+	if err = c.parseSource(driver, c.Source); err != nil {
 		return errors.WithMessage(err, "source is invalid")
 	}
 	if c.ConnMaxIdleTime < time.Second || c.ConnMaxIdleTime > time.Hour*24 {
@@ -98,6 +184,72 @@ func (c Config) Validate() error { //nolint:revive // cyclomatic
 	if _, err = parseLogLevel(c.LogLevel); err != nil {
 		return errors.WithMessage(err, "log_level is invalid")
 	}
+	if c.SlowThreshold < 0 {
+		return errors.New("slow_threshold is invalid, must not be negative")
+	}
+	if c.TxMaxRetries < 0 || c.TxMaxRetries > 10 {
+		return errors.New("tx_max_retries is invalid, must between 0 and 10")
+	}
+	if c.TxMaxRetries > 0 && (c.TxRetryBackoff <= 0 || c.TxRetryMaxBackoff < c.TxRetryBackoff) {
+		return errors.New("tx_retry_backoff is invalid, must greater than 0 and not greater than tx_retry_max_backoff")
+	}
+	if err = c.ConnectRetry.validate(); err != nil {
+		return errors.WithMessage(err, "connect_retry is invalid")
+	}
+	if err = c.Sqlite.validate(); err != nil {
+		return errors.WithMessage(err, "sqlite is invalid")
+	}
+	if err = c.Mysql.validate(); err != nil {
+		return errors.WithMessage(err, "mysql is invalid")
+	}
+	for i, replica := range c.Replicas {
+		if err = c.parseSource(driver, replica); err != nil {
+			return errors.WithMessagef(err, "replicas[%d] is invalid", i)
+		}
+	}
+	for i, resolver := range c.Resolvers {
+		if err = resolver.validate(c, driver); err != nil {
+			return errors.WithMessagef(err, "resolvers[%d] is invalid", i)
+		}
+	}
+	return nil
+}
+
+func (c ConnectRetryConfig) validate() error {
+	if c.MaxWait < 0 {
+		return errors.New("max_wait must not be negative")
+	}
+	if c.MaxWait > 0 && (c.Backoff <= 0 || c.MaxBackoff < c.Backoff) {
+		return errors.New("backoff must greater than 0 and not greater than max_backoff")
+	}
+	return nil
+}
+
+func (c Config) parseSource(driver Driver, source string) error {
+	source, err := c.resolveSource(driver, source)
+	if err != nil {
+		return err
+	}
+	return driver.ParseSource(source)
+}
+
+func (c ResolverConfig) validate(config Config, driver Driver) error {
+	if len(c.Tables) == 0 {
+		return errors.New("tables is empty")
+	}
+	if len(c.Sources) == 0 && len(c.Replicas) == 0 {
+		return errors.New("sources and replicas are both empty")
+	}
+	for i, source := range c.Sources {
+		if err := config.parseSource(driver, source); err != nil {
+			return errors.WithMessagef(err, "sources[%d] is invalid", i)
+		}
+	}
+	for i, replica := range c.Replicas {
+		if err := config.parseSource(driver, replica); err != nil {
+			return errors.WithMessagef(err, "replicas[%d] is invalid", i)
+		}
+	}
 	return nil
 }
 
@@ -111,24 +263,13 @@ func (c Config) GetDatabaseName() string {
 	if err != nil {
 		panic(err)
 	}
-	return driver.GetDatabaseName(c.Source)
-}
-
-func SourceDesensitization(source string) string {
-	dbSourceArr := strings.Split(source, "@")
-	if len(dbSourceArr) <= 1 {
-		return source
-	}
-	showUserAndPassword := "*:*"
-	userAndPassword := strings.Split(dbSourceArr[0], ":")
-	if len(userAndPassword) == 2 {
-		showUserAndPassword = fmt.Sprintf(
-			"%s:%s",
-			strings.Repeat("*", len(userAndPassword[0])),
-			strings.Repeat("*", len(userAndPassword[1])),
-		)
-	}
-	return fmt.Sprintf("%s@%s", showUserAndPassword, dbSourceArr[1])
+	// the name doesn't depend on the password, an unreadable password file is
+	// reported by ResolveSource when connecting
+	source, err := c.namingSource(driver, c.Source)
+	if err != nil {
+		source = os.ExpandEnv(c.Source)
+	}
+	return driver.GetDatabaseName(source)
 }
 
 func parseLogLevel(logLevel string) (logger.LogLevel, error) {
@@ -0,0 +1,84 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/armon/go-metrics"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+
+	"github.com/pundiai/go-sdk/log"
+	"github.com/pundiai/go-sdk/telemetry"
+)
+
+// MetricKeyConnectFailures is the number of consecutive failures of the first connection
+var MetricKeyConnectFailures = []string{"db", "connect", "failures"}
+
+// openDB opens the connection of config.Source, retrying with backoff until
+// config.ConnectRetry.MaxWait elapses when the database is not reachable yet.
+func openDB(ctx context.Context, l log.Logger, driver Driver, config Config) (*gorm.DB, error) {
+	labels := []metrics.Label{telemetry.NewLabel(MetricLabelNameDatabase, nameDatabase(driver, config))}
+	retry := config.ConnectRetry
+	deadline := time.Now().Add(retry.MaxWait)
+	backoff := retry.Backoff
+	for attempt := 1; ; attempt++ {
+		db, err := openGorm(l, driver, config)
+		if err == nil {
+			telemetry.SetGaugeWithLabels(MetricKeyConnectFailures, 0, labels)
+			return db, nil
+		}
+		telemetry.SetGaugeWithLabels(MetricKeyConnectFailures, float32(attempt), labels)
+		if retry.MaxWait <= 0 || time.Now().Add(backoff).After(deadline) {
+			return nil, err
+		}
+		l.Warn("db connect retry", "attempt", attempt, "backoff", backoff, "error", err)
+		select {
+		case <-ctx.Done():
+			return nil, err
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, retry.MaxBackoff)
+	}
+}
+
+// nameDatabase names the database in the connect metrics and the health check,
+// falling back to the driver name for the sources which the driver can't name,
+// such as :memory:
+func nameDatabase(driver Driver, config Config) string {
+	source, err := config.resolveSource(driver, config.Source)
+	if err != nil || driver.ParseSource(source) != nil {
+		return config.Driver
+	}
+	return driver.GetDatabaseName(source)
+}
+
+// openGorm makes a single connection attempt. The dialector is created again
+// every time since it owns the pool, which gorm.Open leaves open when the ping fails.
+func openGorm(l log.Logger, driver Driver, config Config) (*gorm.DB, error) {
+	dialector, err := openSource(driver, config, config.Source)
+	if err != nil {
+		return nil, errors.WithMessage(err, "resolve source error")
+	}
+	db, err := gorm.Open(dialector, newGormConfig(l, config))
+	if err != nil {
+		if db != nil {
+			if sqlDB, dbErr := db.DB(); dbErr == nil {
+				_ = sqlDB.Close()
+			}
+		}
+		return nil, errors.Wrap(err, "db open error")
+	}
+	return db, nil
+}
+
+func newGormConfig(l log.Logger, config Config) *gorm.Config {
+	return &gorm.Config{
+		NamingStrategy: schema.NamingStrategy{
+			SingularTable: true,
+		},
+
+		Logger: NewGormLogger(l, config),
+	}
+}
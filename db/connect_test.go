@@ -0,0 +1,106 @@
+package db_test
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/armon/go-metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pundiai/go-sdk/db"
+	"github.com/pundiai/go-sdk/log"
+)
+
+func TestNewDBConnectRetry(t *testing.T) {
+	sink := metrics.NewInmemSink(time.Minute, time.Minute)
+	metricsConf := metrics.DefaultConfig("test")
+	metricsConf.EnableHostname = false
+	metricsConf.EnableRuntimeMetrics = false
+	_, err := metrics.NewGlobal(metricsConf, sink)
+	require.NoError(t, err)
+
+	// a directory takes the place of the db file until it is removed a little later
+	source := filepath.Join(t.TempDir(), "connect-retry.db")
+	require.NoError(t, os.Mkdir(source, 0o700))
+	config := db.NewDefConfig()
+	config.Source = source
+	config.EnableMetric = false
+	config.ConnectRetry = db.ConnectRetryConfig{
+		MaxWait:    5 * time.Second,
+		Backoff:    20 * time.Millisecond,
+		MaxBackoff: 100 * time.Millisecond,
+	}
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		assert.NoError(t, os.Remove(source))
+	}()
+
+	testDB, err := db.NewDB(context.Background(), log.NewNopLogger(), config)
+	require.NoError(t, err)
+	defer testDB.Close()
+	require.NoError(t, testDB.Ping(context.Background()))
+
+	// the gauge is reset in the latest interval, an earlier one keeps the failures
+	intervals := sink.Data()
+	gauges := intervals[len(intervals)-1].Gauges
+	require.Contains(t, gauges, "test.db.connect.failures;database=connect-retry")
+	assert.Zero(t, gauges["test.db.connect.failures;database=connect-retry"].Value)
+}
+
+func TestNewDBConnectRetryTimeout(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	port := listener.Addr().(*net.TCPAddr).Port
+	require.NoError(t, listener.Close())
+
+	config := db.NewDefConfig()
+	config.Driver = db.MysqlDriver
+	config.Source = "root:root@tcp(127.0.0.1:" + strconv.Itoa(port) + ")/my"
+	config.EnableMetric = false
+	config.ConnectRetry = db.ConnectRetryConfig{
+		MaxWait:    200 * time.Millisecond,
+		Backoff:    20 * time.Millisecond,
+		MaxBackoff: 50 * time.Millisecond,
+	}
+	start := time.Now()
+	_, err = db.NewDB(context.Background(), log.NewNopLogger(), config)
+	require.ErrorContains(t, err, "db open error")
+	assert.GreaterOrEqual(t, time.Since(start), 100*time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	config.ConnectRetry.MaxWait = time.Minute
+	_, err = db.NewDB(ctx, log.NewNopLogger(), config)
+	require.ErrorContains(t, err, "db open error")
+}
+
+func TestConnectRetryConfigValidate(t *testing.T) {
+	config := db.NewDefConfig()
+	config.ConnectRetry.MaxWait = -time.Second
+	require.EqualError(t, config.Validate(), "connect_retry is invalid: max_wait must not be negative")
+
+	config.ConnectRetry.MaxWait = time.Minute
+	config.ConnectRetry.MaxBackoff = time.Millisecond
+	require.EqualError(t, config.Validate(), "connect_retry is invalid: backoff must greater than 0 and not greater than max_backoff")
+}
+
+func TestNewDBUnnamedSource(t *testing.T) {
+	config := db.NewDefConfig()
+	config.Source = ":memory:"
+	config.EnableMetric = false
+	testDB, err := db.NewDB(context.Background(), log.NewNopLogger(), config)
+	require.NoError(t, err)
+	defer testDB.Close()
+	require.NoError(t, testDB.Ping(context.Background()))
+
+	config.Driver = db.MysqlDriver
+	config.Source = "root@tcp(127.0.0.1:1)/my"
+	_, err = db.NewDB(context.Background(), log.NewNopLogger(), config)
+	require.ErrorContains(t, err, "db open error")
+}
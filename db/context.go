@@ -0,0 +1,33 @@
+package db
+
+import (
+	"context"
+)
+
+type ctxKeyTx struct{}
+
+var keyTx = ctxKeyTx{}
+
+// WithTx returns a copy of ctx carrying tx, the code receiving ctx joins tx
+// through FromContext
+func WithTx(ctx context.Context, tx DB) context.Context {
+	return context.WithValue(ctx, keyTx, tx)
+}
+
+// FromContext returns the transaction carried by ctx, or def when there is none
+func FromContext(ctx context.Context, def DB) DB {
+	if tx, ok := ctx.Value(keyTx).(DB); ok {
+		return tx
+	}
+	return def
+}
+
+// Transaction runs fn in a transaction on def, fn receives a ctx carrying the
+// transaction so that every DAO called with it commits or rolls back as one unit.
+// When ctx already carries a transaction, fn runs nested in it on a savepoint
+// and only its own changes are rolled back when it fails.
+func Transaction(ctx context.Context, def DB, fn func(ctx context.Context) error) error {
+	return FromContext(ctx, def).WithContext(ctx).Transaction(func(tx DB) error {
+		return fn(WithTx(ctx, tx))
+	})
+}
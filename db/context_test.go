@@ -0,0 +1,58 @@
+package db_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/pundiai/go-sdk/db"
+	"github.com/pundiai/go-sdk/log"
+)
+
+func TestContextTransaction(t *testing.T) {
+	testDB := db.NewMemoryDB(log.LevelError, "context-tx")
+	require.NoError(t, testDB.AutoMigrate(&gorm.Model{}))
+	count := func() int64 {
+		var count int64
+		testDB.Model(&gorm.Model{}).Count(&count)
+		return count
+	}
+	ctx := context.Background()
+	assert.Equal(t, testDB, db.FromContext(ctx, testDB))
+
+	errRollback := errors.New("rollback")
+	require.ErrorIs(t, db.Transaction(ctx, testDB, func(ctx context.Context) error {
+		tx := db.FromContext(ctx, testDB)
+		assert.NotEqual(t, testDB, tx)
+		require.NoError(t, tx.Create(&gorm.Model{ID: 1}))
+		return errRollback
+	}), errRollback)
+	assert.Zero(t, count())
+
+	require.NoError(t, db.Transaction(ctx, testDB, func(ctx context.Context) error {
+		require.NoError(t, db.FromContext(ctx, testDB).Create(&gorm.Model{ID: 1}))
+		// the nested transaction rolls back to its savepoint only
+		require.ErrorIs(t, db.Transaction(ctx, testDB, func(ctx context.Context) error {
+			require.NoError(t, db.FromContext(ctx, testDB).Create(&gorm.Model{ID: 2}))
+			return errRollback
+		}), errRollback)
+		return db.Transaction(ctx, testDB, func(ctx context.Context) error {
+			return db.FromContext(ctx, testDB).Create(&gorm.Model{ID: 3})
+		})
+	}))
+	var models []gorm.Model
+	require.NoError(t, testDB.Order("id").Find(&models))
+	require.Len(t, models, 2)
+	assert.EqualValues(t, 1, models[0].ID)
+	assert.EqualValues(t, 3, models[1].ID)
+
+	tx := testDB.Begin()
+	txCtx := db.WithTx(ctx, tx)
+	require.NoError(t, db.FromContext(txCtx, testDB).Create(&gorm.Model{ID: 4}))
+	require.NoError(t, tx.Rollback())
+	assert.EqualValues(t, 2, count())
+}
@@ -2,16 +2,16 @@ package db
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
-	golog "log"
-	"os"
+	"reflect"
+	"slices"
 	"strings"
 	"time"
 
 	"github.com/pkg/errors"
 	"gorm.io/gorm"
-	"gorm.io/gorm/logger"
-	"gorm.io/gorm/schema"
+	"gorm.io/gorm/clause"
 	"gorm.io/plugin/dbresolver"
 	"gorm.io/plugin/prometheus"
 
@@ -27,22 +27,67 @@ type DB interface {
 	Order(value any) DB
 	Count(count *int64) DB
 	Group(query string) DB
+	Having(query any, args ...any) DB
+	// Joins joins a named association of the model, or the raw join in query
+	Joins(query string, args ...any) DB
+	// Preload loads the named association with a separate query, conditions in
+	// args filter the preloaded rows
+	Preload(query string, args ...any) DB
+	Omit(columns ...string) DB
+	// Clauses adds clause expressions such as clause.OnConflict or clause.Returning
+	Clauses(conds ...clause.Expression) DB
+	// Raw replaces the query with sql, run it by Scan, Find or Rows
+	Raw(sql string, values ...any) DB
 	RowsAffected(number int64) DB
 
 	Select(query any, args ...any) DB
 	Distinct(args ...any) DB
+	// ForUpdate locks the selected rows until the transaction ends, skipLocked skips the
+	// rows locked by others and noWait fails instead of waiting for them.
+	// It is a no-op on sqlite, which locks the whole database on write.
+	ForUpdate(skipLocked, noWait bool) DB
 	Find(dest any, conds ...any) (err error)
 	First(dest any, conds ...any) (found bool, err error)
 	MustFirst(dest any, conds ...any) (err error)
+	// Scan scans the query result into dest, a struct, a slice or a map,
+	// without the model hooks and soft delete filter
+	Scan(dest any) error
+	// Pluck queries a single column into dest, a pointer to a slice
+	Pluck(column string, dest any) error
+	// Assign sets attrs on the record found or created by FirstOrCreate
+	Assign(attrs ...any) DB
+	// FirstOrCreate finds the first record matching conds, or creates it
+	FirstOrCreate(dest any, conds ...any) error
+	// FindInBatches queries records in batches of batchSize ordered by primary key,
+	// dest is overwritten with each batch before fn is called
+	FindInBatches(dest any, batchSize int, fn func(tx DB, batch int) error) error
+	// Rows returns a cursor over the query result, the caller must close it
+	Rows() (Rows, error)
+	// Each scans the query result row by row into dest and calls fn after every row
+	Each(dest any, fn func() error) error
+	// Paginate finds one page of dest, a pointer to a slice of models embedding
+	// model.Base, by keyset instead of offset
+	Paginate(dest any, req PageRequest) (Page, error)
 
 	Exec(sql string, values ...any) error
 
 	Create(value any) error
+	// Upsert inserts value, or updates updateColumns (all columns when empty) of the
+	// rows conflicting on conflictColumns. conflictColumns are required by sqlite and
+	// postgres, mysql resolves conflicts on any unique key and ignores them.
+	Upsert(value any, conflictColumns []string, updateColumns []string) error
+	// CreateIgnore inserts value and skips the rows conflicting with existing ones,
+	// it returns the number of rows inserted, only those get their id set.
+	CreateIgnore(value any) (int64, error)
+	// CreateInBatches inserts the values slice in one transaction, splitting it into
+	// batches no larger than batchSize and the driver placeholder limit
+	CreateInBatches(values any, batchSize int) error
 	Update(column string, value any) error
 	Updates(values any) error
 	Delete(value any, conds ...any) error
 
 	Transaction(fn func(tx DB) error) error
+	TransactionWithOptions(opts TxOptions, fn func(tx DB) error) error
 
 	Begin() DB
 	Commit() error
@@ -50,40 +95,47 @@ type DB interface {
 
 	AutoMigrate(dst ...any) error
 
+	// GetSource returns the configured source, its file:// password reference is
+	// left unread, use ResolveSource to connect with it
 	GetSource() string
+	// ResolveSource returns the source with its credentials resolved, the password
+	// file is read again on every call
+	ResolveSource() (string, error)
 	GetDriver() Driver
+	// Ping verifies a connection to the primary source is still alive
+	Ping(ctx context.Context) error
+	// Stats returns the connection pool statistics of the primary source
+	Stats() sql.DBStats
 	Close() error
 
 	WithContext(ctx context.Context) DB
 	WithLogger(l log.Logger) DB
+	// UsePrimary forces the following queries to the primary source, for read-after-write paths
+	UsePrimary() DB
+	// Unscoped includes the soft deleted rows in the following queries, and
+	// deletes the rows permanently
+	Unscoped() DB
 }
 
-func NewDB(_ context.Context, l log.Logger, config Config) (DB, error) {
+type TxOptions struct {
+	Isolation sql.IsolationLevel
+	ReadOnly  bool
+	// Timeout rolls back the transaction when it is exceeded, zero means no timeout
+	Timeout time.Duration
+}
+
+func NewDB(ctx context.Context, l log.Logger, config Config) (DB, error) {
 	driver, err := GetDriver(config.Driver)
 	if err != nil {
 		return nil, errors.WithMessage(err, "get driver error")
 	}
 
-	dblog := logger.New(
-		golog.New(os.Stdout, "\r\n", golog.LstdFlags), // io writer
-		logger.Config{
-			SlowThreshold:             time.Second * 2,      // Slow SQL threshold
-			LogLevel:                  config.GetLogLevel(), // Log level
-			IgnoreRecordNotFoundError: true,                 // Ignore ErrRecordNotFound error for logger
-			ParameterizedQueries:      false,                // Don't include params in the SQL log
-			Colorful:                  false,                // Disable color
-		},
-	)
-
-	db, err := gorm.Open(driver.Open(os.ExpandEnv(config.Source)), &gorm.Config{
-		NamingStrategy: schema.NamingStrategy{
-			SingularTable: true,
-		},
-
-		Logger: dblog,
-	})
+	db, err := openDB(ctx, l, driver, config)
 	if err != nil {
-		return nil, errors.Wrap(err, "db open error")
+		return nil, err
+	}
+	if err = db.Use(&optimisticLockPlugin{}); err != nil {
+		return nil, errors.Wrap(err, "db use optimistic lock error")
 	}
 	if config.EnableMetric {
 		prometheusCfg := prometheus.Config{
@@ -94,8 +146,21 @@ func NewDB(_ context.Context, l log.Logger, config Config) (DB, error) {
 			return nil, errors.Wrap(err, "db use prometheus error")
 		}
 	}
+	if config.EnableStatementMetric {
+		if err = db.Use(newMetricsPlugin(config.GetDatabaseName())); err != nil {
+			return nil, errors.Wrap(err, "db use statement metrics error")
+		}
+	}
+	if config.EnableTracing {
+		if err = db.Use(newTracingPlugin(config.Driver, config.GetDatabaseName())); err != nil {
+			return nil, errors.Wrap(err, "db use tracing error")
+		}
+	}
 
-	register := dbresolver.Register(dbresolver.Config{})
+	register, err := newResolver(driver, config)
+	if err != nil {
+		return nil, errors.WithMessage(err, "resolve replicas error")
+	}
 	register = register.SetConnMaxIdleTime(config.ConnMaxIdleTime)
 	register = register.SetConnMaxLifetime(config.ConnMaxLifeTime)
 	register = register.SetMaxOpenConns(config.MaxOpenConn)
@@ -107,6 +172,30 @@ func NewDB(_ context.Context, l log.Logger, config Config) (DB, error) {
 	return newGDB(l, &config, db, driver, 0), nil
 }
 
+// newResolver registers the replicas and the per table sources of config
+func newResolver(driver Driver, config Config) (*dbresolver.DBResolver, error) {
+	replicas, err := openSources(driver, config, config.Replicas)
+	if err != nil {
+		return nil, err
+	}
+	register := dbresolver.Register(dbresolver.Config{Replicas: replicas})
+	for _, resolver := range config.Resolvers {
+		tables := make([]any, 0, len(resolver.Tables))
+		for _, table := range resolver.Tables {
+			tables = append(tables, table)
+		}
+		sources, err := openSources(driver, config, resolver.Sources)
+		if err != nil {
+			return nil, err
+		}
+		if replicas, err = openSources(driver, config, resolver.Replicas); err != nil {
+			return nil, err
+		}
+		register = register.Register(dbresolver.Config{Sources: sources, Replicas: replicas}, tables...)
+	}
+	return register, nil
+}
+
 func NewMemoryDB(logLevel, name string, arg ...string) DB {
 	l, err := log.NewLogger(log.FormatConsole, logLevel)
 	if err != nil {
@@ -156,6 +245,14 @@ func (g *gDB) GetSource() string {
 	return g.config.Source
 }
 
+func (g *gDB) ResolveSource() (string, error) {
+	source, err := g.config.ResolveSource()
+	if err != nil {
+		return "", errors.WithMessage(err, "db resolve source error")
+	}
+	return source, nil
+}
+
 func newGDB(logger log.Logger, config *Config, db *gorm.DB, driver Driver, rowsAffected int64) *gDB {
 	return &gDB{
 		logger: logger,
@@ -173,19 +270,53 @@ func (g *gDB) copy(db *gorm.DB) DB {
 }
 
 func (g *gDB) WithLogger(logger log.Logger) DB {
-	return newGDB(logger, g.config, g.db, g.driver, g.rowsAffected)
+	db := g.db.Session(&gorm.Session{Logger: NewGormLogger(logger, *g.config)})
+	return newGDB(logger, g.config, db, g.driver, g.rowsAffected)
 }
 
 func (g *gDB) RowsAffected(number int64) DB {
 	return newGDB(g.logger, g.config, g.db, g.driver, number)
 }
 
+// Close closes the pools of the primary source, the replicas and the resolver
+// sources, it returns the first error
 func (g *gDB) Close() error {
 	sqlDB, err := g.db.DB()
 	if err != nil {
 		return nil
 	}
-	return sqlDB.Close()
+	pools := []*sql.DB{sqlDB}
+	// dbresolver opens a pool for every replica and source without closing them
+	if register, ok := g.db.Config.Plugins[(&dbresolver.DBResolver{}).Name()].(*dbresolver.DBResolver); ok {
+		_ = register.Call(func(connPool gorm.ConnPool) error {
+			if pool, ok := connPool.(*sql.DB); ok && !slices.Contains(pools, pool) {
+				pools = append(pools, pool)
+			}
+			return nil
+		})
+	}
+	for _, pool := range pools {
+		if closeErr := pool.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+func (g *gDB) Ping(ctx context.Context) error {
+	sqlDB, err := g.db.DB()
+	if err != nil {
+		return errors.Wrap(err, "db ping error")
+	}
+	return errors.Wrap(sqlDB.PingContext(ctx), "db ping error")
+}
+
+func (g *gDB) Stats() sql.DBStats {
+	sqlDB, err := g.db.DB()
+	if err != nil {
+		return sql.DBStats{}
+	}
+	return sqlDB.Stats()
 }
 
 func (g *gDB) GetDriver() Driver {
@@ -196,6 +327,14 @@ func (g *gDB) WithContext(ctx context.Context) DB {
 	return g.copy(g.db.WithContext(ctx))
 }
 
+func (g *gDB) Unscoped() DB {
+	return g.copy(g.db.Unscoped())
+}
+
+func (g *gDB) UsePrimary() DB {
+	return g.copy(g.db.Clauses(dbresolver.Write))
+}
+
 func (g *gDB) Model(value any) DB {
 	return g.copy(g.db.Model(value))
 }
@@ -208,13 +347,13 @@ func (g *gDB) Limit(limit int) DB {
 	return g.copy(g.db.Limit(limit))
 }
 
+// Scopes applies funcs in order, each one receives the DB returned by the previous
 func (g *gDB) Scopes(funcs ...func(DB) DB) DB {
-	fns := make([]func(db *gorm.DB) *gorm.DB, 0, len(funcs))
-	for _, f := range funcs {
-		fn := f
-		fns = append(fns, func(db *gorm.DB) *gorm.DB { fn(g.copy(db)); return db })
+	var db DB = g
+	for _, fn := range funcs {
+		db = fn(db)
 	}
-	return g.copy(g.db.Scopes(fns...))
+	return db
 }
 
 func (g *gDB) Offset(offset int) DB {
@@ -237,6 +376,47 @@ func (g *gDB) Distinct(args ...any) DB {
 	return g.copy(g.db.Distinct(args...))
 }
 
+func (g *gDB) Having(query any, args ...any) DB {
+	return g.copy(g.db.Having(query, args...))
+}
+
+func (g *gDB) Joins(query string, args ...any) DB {
+	return g.copy(g.db.Joins(query, args...))
+}
+
+func (g *gDB) Preload(query string, args ...any) DB {
+	return g.copy(g.db.Preload(query, args...))
+}
+
+func (g *gDB) Omit(columns ...string) DB {
+	return g.copy(g.db.Omit(columns...))
+}
+
+func (g *gDB) Clauses(conds ...clause.Expression) DB {
+	return g.copy(g.db.Clauses(conds...))
+}
+
+func (g *gDB) Raw(sql string, values ...any) DB {
+	return g.copy(g.db.Raw(sql, values...))
+}
+
+func (g *gDB) Assign(attrs ...any) DB {
+	return g.copy(g.db.Assign(attrs...))
+}
+
+func (g *gDB) ForUpdate(skipLocked, noWait bool) DB {
+	if skipLocked && noWait {
+		tx := g.db.Clauses()
+		_ = tx.AddError(errors.New("skip locked and no wait are mutually exclusive"))
+		return g.copy(tx)
+	}
+	locking := forUpdateClause(g.driver, skipLocked, noWait)
+	if locking == nil {
+		return g.copy(g.db)
+	}
+	return g.copy(g.db.Clauses(locking))
+}
+
 func (g *gDB) Select(query any, args ...any) DB {
 	return g.copy(g.db.Select(query, args...))
 }
@@ -245,11 +425,108 @@ func (g *gDB) Find(dest any, conds ...any) error {
 	err := g.db.Find(dest, conds...).Error
 	if err != nil {
 		g.logger.Error("db find error", "dest", dest, "conds", conds, "error", err)
-		return errors.Wrap(err, "db find error")
+		return errors.Wrap(classifyError(g.driver, err), "db find error")
 	}
 	return nil
 }
 
+func (g *gDB) Scan(dest any) error {
+	err := g.db.Scan(dest).Error
+	if err != nil {
+		g.logger.Error("db scan error", "dest", dest, "error", err)
+		return errors.Wrap(classifyError(g.driver, err), "db scan error")
+	}
+	return nil
+}
+
+func (g *gDB) Pluck(column string, dest any) error {
+	err := g.db.Pluck(column, dest).Error
+	if err != nil {
+		g.logger.Error("db pluck error", "column", column, "dest", dest, "error", err)
+		return errors.Wrap(classifyError(g.driver, err), "db pluck error")
+	}
+	return nil
+}
+
+func (g *gDB) FirstOrCreate(dest any, conds ...any) error {
+	err := g.db.FirstOrCreate(dest, conds...).Error
+	if err != nil {
+		g.logger.Error("db first or create error", "dest", dest, "conds", conds, "error", err)
+		return errors.Wrap(classifyError(g.driver, err), "db first or create error")
+	}
+	return nil
+}
+
+func (g *gDB) Paginate(dest any, req PageRequest) (Page, error) {
+	page, err := g.paginate(dest, req)
+	if err != nil {
+		g.logger.Error("db paginate error", "dest", dest, "request", req, "error", err)
+		return Page{}, errors.Wrap(classifyError(g.driver, err), "db paginate error")
+	}
+	return page, nil
+}
+
+func (g *gDB) paginate(dest any, req PageRequest) (Page, error) {
+	slice := reflect.ValueOf(dest)
+	if slice.Kind() != reflect.Pointer || slice.Elem().Kind() != reflect.Slice {
+		return Page{}, errors.New("dest must be a pointer to slice")
+	}
+	slice = slice.Elem()
+	column, desc, err := req.order()
+	if err != nil {
+		return Page{}, err
+	}
+	if err = checkPageElem(slice.Type().Elem(), column); err != nil {
+		return Page{}, err
+	}
+	limit, err := req.limit()
+	if err != nil {
+		return Page{}, err
+	}
+	if req.After != "" && req.Before != "" {
+		return Page{}, errors.New("after and before are mutually exclusive")
+	}
+	// an order added before the keyset one would break the cursors
+	if _, ok := g.db.Statement.Clauses["ORDER BY"]; ok {
+		return Page{}, errors.New("query is already ordered, order by the page request instead")
+	}
+
+	var cursor *pageCursor
+	backward := req.Before != ""
+	if token := req.After + req.Before; token != "" {
+		if cursor, err = decodePageCursor(column, token); err != nil {
+			return Page{}, err
+		}
+	}
+	where, orderBy := keysetClauses(column, desc, backward, cursor)
+	query := g.db.Clauses(orderBy)
+	if where != nil {
+		query = query.Clauses(clause.Where{Exprs: []clause.Expression{where}})
+	}
+	if err = query.Limit(limit + 1).Find(dest).Error; err != nil {
+		return Page{}, err
+	}
+
+	more := slice.Len() > limit
+	if more {
+		slice.Set(slice.Slice(0, limit))
+	}
+	if backward {
+		reverseSlice(slice)
+	}
+	var page Page
+	if slice.Len() == 0 {
+		return page, nil
+	}
+	if more || backward {
+		page.Next = encodePageCursor(column, slice.Index(slice.Len()-1))
+	}
+	if (more && backward) || req.After != "" {
+		page.Prev = encodePageCursor(column, slice.Index(0))
+	}
+	return page, nil
+}
+
 func (g *gDB) First(dest any, conds ...any) (bool, error) {
 	err := g.db.First(dest, conds...).Error
 	if err != nil {
@@ -257,21 +534,116 @@ func (g *gDB) First(dest any, conds ...any) (bool, error) {
 			return false, nil
 		}
 		g.logger.Error("db first error", "dest", dest, "conds", conds, "error", err)
-		return false, errors.Wrap(err, "db first error")
+		return false, errors.Wrap(classifyError(g.driver, err), "db first error")
 	}
 	return true, nil
 }
 
+func (g *gDB) FindInBatches(dest any, batchSize int, fn func(tx DB, batch int) error) error {
+	ctx := g.db.Statement.Context
+	err := g.db.FindInBatches(dest, batchSize, func(tx *gorm.DB, batch int) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		return fn(g.copy(tx), batch)
+	}).Error
+	if err != nil {
+		g.logger.Error("db find in batches error", "dest", dest, "batch size", batchSize, "error", err)
+		return errors.Wrap(classifyError(g.driver, err), "db find in batches error")
+	}
+	return nil
+}
+
+func (g *gDB) Rows() (Rows, error) {
+	rows, err := g.db.Rows()
+	if err != nil {
+		g.logger.Error("db rows error", "error", err)
+		return nil, errors.Wrap(err, "db rows error")
+	}
+	return &gRows{rows: rows, db: g.db}, nil
+}
+
+func (g *gDB) Each(dest any, fn func() error) error {
+	rows, err := g.Rows()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = rows.Close() }()
+
+	ctx := g.db.Statement.Context
+	for rows.Next() {
+		if err = ctx.Err(); err != nil {
+			break
+		}
+		if err = rows.Scan(dest); err != nil {
+			break
+		}
+		if err = fn(); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = rows.Err()
+	}
+	if err != nil {
+		g.logger.Error("db each error", "dest", dest, "error", err)
+		return errors.Wrap(classifyError(g.driver, err), "db each error")
+	}
+	return nil
+}
+
 func (g *gDB) MustFirst(dest any, conds ...any) error {
-	return errors.Wrap(g.db.First(dest, conds...).Error, "db must first error")
+	if err := g.db.First(dest, conds...).Error; err != nil {
+		return errors.Wrap(classifyError(g.driver, err), "db must first error")
+	}
+	return nil
 }
 
 func (g *gDB) Transaction(fn func(tx DB) error) error {
-	return g.db.Transaction(func(tx *gorm.DB) error {
-		return fn(g.copy(tx))
+	return g.retryTransaction(func() error {
+		return g.db.Transaction(func(tx *gorm.DB) error {
+			return fn(g.copy(tx))
+		})
+	})
+}
+
+func (g *gDB) TransactionWithOptions(opts TxOptions, fn func(tx DB) error) error {
+	return g.retryTransaction(func() error {
+		db := g.db
+		if opts.Timeout > 0 {
+			ctx, cancel := context.WithTimeout(db.Statement.Context, opts.Timeout)
+			defer cancel()
+			db = db.WithContext(ctx)
+		}
+		return db.Transaction(func(tx *gorm.DB) error {
+			return fn(g.copy(tx))
+		}, &sql.TxOptions{Isolation: opts.Isolation, ReadOnly: opts.ReadOnly})
 	})
 }
 
+// retryTransaction re-runs a top level transaction while the driver classifies
+// its error as retryable, nested transactions are left to the outermost one.
+func (g *gDB) retryTransaction(run func() error) error {
+	if _, inTx := g.db.Statement.ConnPool.(gorm.TxCommitter); inTx || g.config.TxMaxRetries <= 0 {
+		return run()
+	}
+	ctx := g.db.Statement.Context
+	backoff := g.config.TxRetryBackoff
+	for attempt := 1; ; attempt++ {
+		err := run()
+		if err == nil || attempt > g.config.TxMaxRetries || !g.driver.IsRetryableError(err) {
+			return err
+		}
+		g.logger.Warn("db transaction retry", "attempt", attempt, "backoff", backoff, "error", err)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, g.config.TxRetryMaxBackoff)
+	}
+}
+
 func (g *gDB) Begin() DB {
 	return g.copy(g.db.Begin())
 }
@@ -288,24 +660,133 @@ func (g *gDB) Create(value any) error {
 	tx := g.db.Create(value)
 	if err := tx.Error; err != nil {
 		g.logger.Error("db create error", "value", value, "error", err)
-		return errors.Wrap(err, "db create error")
+		return errors.Wrap(classifyError(g.driver, err), "db create error")
 	}
 	if (g.rowsAffected == 0 && tx.RowsAffected != 1) || (g.rowsAffected > 0 && tx.RowsAffected != g.rowsAffected) {
 		g.logger.Error("db create error", "value", value, "rows affected", tx.RowsAffected)
-		return errors.Errorf("db create error, rows affected: %d, expected: 1", tx.RowsAffected)
+		return rowsAffectedError("db create error, rows affected: %d, expected: 1", tx.RowsAffected)
+	}
+	return nil
+}
+
+func (g *gDB) Upsert(value any, conflictColumns, updateColumns []string) error {
+	onConflict := clause.OnConflict{}
+	for _, column := range conflictColumns {
+		onConflict.Columns = append(onConflict.Columns, clause.Column{Name: column})
+	}
+	if len(updateColumns) > 0 {
+		onConflict.DoUpdates = clause.AssignmentColumns(updateColumns)
+	} else {
+		onConflict.UpdateAll = true
+	}
+	tx := g.db.Clauses(onConflict).Create(value)
+	if err := tx.Error; err != nil {
+		g.logger.Error("db upsert error", "value", value, "error", err)
+		return errors.Wrap(classifyError(g.driver, err), "db upsert error")
+	}
+	minimum, maximum := g.rowsAffected, g.rowsAffected
+	if g.rowsAffected == 0 {
+		minimum, maximum = upsertRowsAffected(g.driver, countRows(value))
+	}
+	if tx.RowsAffected < minimum || tx.RowsAffected > maximum {
+		g.logger.Error("db upsert error", "value", value, "rows affected", tx.RowsAffected)
+		return rowsAffectedError("db upsert error, rows affected: %d, expected: [%d, %d]", tx.RowsAffected, minimum, maximum)
 	}
 	return nil
 }
 
+// CreateIgnore inserts the rows of a slice one by one in a transaction, gorm
+// assigns the ids returned by a multi-row insert by position, which gives them to
+// the wrong rows as soon as one is skipped.
+func (g *gDB) CreateIgnore(value any) (int64, error) {
+	rows := reflect.Indirect(reflect.ValueOf(value))
+	if rows.Kind() != reflect.Slice && rows.Kind() != reflect.Array {
+		return g.createIgnore(g.db, value)
+	}
+	var created int64
+	err := g.db.Transaction(func(tx *gorm.DB) error {
+		for i := range rows.Len() {
+			row := rows.Index(i)
+			if row.Kind() != reflect.Pointer && row.CanAddr() {
+				row = row.Addr()
+			}
+			inserted, err := g.createIgnore(tx, row.Interface())
+			if err != nil {
+				return err
+			}
+			created += inserted
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return created, nil
+}
+
+func (g *gDB) createIgnore(db *gorm.DB, value any) (int64, error) {
+	tx := db.Clauses(clause.OnConflict{DoNothing: true}).Create(value)
+	if err := tx.Error; err != nil {
+		g.logger.Error("db create ignore error", "value", value, "error", err)
+		return 0, errors.Wrap(classifyError(g.driver, err), "db create ignore error")
+	}
+	if tx.RowsAffected > 1 {
+		g.logger.Error("db create ignore error", "value", value, "rows affected", tx.RowsAffected)
+		return 0, rowsAffectedError("db create ignore error, rows affected: %d, expected at most: %d", tx.RowsAffected, 1)
+	}
+	return tx.RowsAffected, nil
+}
+
+func (g *gDB) CreateInBatches(values any, batchSize int) error {
+	kind := reflect.Indirect(reflect.ValueOf(values)).Kind()
+	if kind != reflect.Slice && kind != reflect.Array {
+		return errors.Errorf("db create in batches error, values must be a slice, got: %T", values)
+	}
+	if batchSize <= 0 {
+		return errors.Errorf("db create in batches error, invalid batch size: %d", batchSize)
+	}
+	stmt := &gorm.Statement{DB: g.db}
+	if err := stmt.Parse(values); err != nil {
+		return errors.Wrap(err, "db create in batches error")
+	}
+	if columns := len(stmt.Schema.DBNames); columns > 0 && batchSize*columns > maxPlaceholders(g.driver) {
+		batchSize = max(maxPlaceholders(g.driver)/columns, 1)
+	}
+
+	tx := g.db.CreateInBatches(values, batchSize)
+	if err := tx.Error; err != nil {
+		g.logger.Error("db create in batches error", "batch size", batchSize, "error", err)
+		return errors.Wrap(classifyError(g.driver, err), "db create in batches error")
+	}
+	expected := g.rowsAffected
+	if expected == 0 {
+		expected = countRows(values)
+	}
+	if tx.RowsAffected != expected {
+		g.logger.Error("db create in batches error", "batch size", batchSize, "rows affected", tx.RowsAffected)
+		return rowsAffectedError("db create in batches error, rows affected: %d, expected: %d", tx.RowsAffected, expected)
+	}
+	return nil
+}
+
+// countRows returns the number of records in value, which is either a single model or a slice of them
+func countRows(value any) int64 {
+	rv := reflect.Indirect(reflect.ValueOf(value))
+	if rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array {
+		return int64(rv.Len())
+	}
+	return 1
+}
+
 func (g *gDB) Update(column string, value any) error {
 	tx := g.db.Update(column, value)
 	if err := tx.Error; err != nil {
 		g.logger.Error("db update error", "column", column, "value", value, "error", err)
-		return errors.Wrap(err, "db update error")
+		return errors.Wrap(classifyError(g.driver, err), "db update error")
 	}
 	if g.rowsAffected > 0 && tx.RowsAffected != g.rowsAffected {
 		g.logger.Error("db update error", "column", column, "value", value, "rows affected", tx.RowsAffected)
-		return errors.Errorf("db update error, rows affected: %d, expected: %d", tx.RowsAffected, g.rowsAffected)
+		return rowsAffectedError("db update error, rows affected: %d, expected: %d", tx.RowsAffected, g.rowsAffected)
 	}
 	return nil
 }
@@ -314,11 +795,11 @@ func (g *gDB) Updates(values any) error {
 	tx := g.db.Updates(values)
 	if err := tx.Error; err != nil {
 		g.logger.Error("db updates error", "values", values, "error", err)
-		return errors.Wrap(err, "db updates error")
+		return errors.Wrap(classifyError(g.driver, err), "db updates error")
 	}
 	if g.rowsAffected > 0 && tx.RowsAffected != g.rowsAffected {
 		g.logger.Error("db updates error", "values", values, "rows affected", tx.RowsAffected)
-		return errors.Errorf("db updates error, rows affected: %d, expected: %d", tx.RowsAffected, g.rowsAffected)
+		return rowsAffectedError("db updates error, rows affected: %d, expected: %d", tx.RowsAffected, g.rowsAffected)
 	}
 	return nil
 }
@@ -327,11 +808,11 @@ func (g *gDB) Delete(value any, conds ...any) error {
 	tx := g.db.Delete(value, conds...)
 	if err := tx.Error; err != nil {
 		g.logger.Error("db delete error", "value", value, "conds", conds, "error", err)
-		return errors.Wrap(err, "db delete error")
+		return errors.Wrap(classifyError(g.driver, err), "db delete error")
 	}
 	if g.rowsAffected > 0 && tx.RowsAffected != g.rowsAffected {
 		g.logger.Error("db delete error", "value", value, "conds", conds, "rows affected", tx.RowsAffected)
-		return errors.Errorf("db delete error, rows affected: %d, expected: %d", tx.RowsAffected, g.rowsAffected)
+		return rowsAffectedError("db delete error, rows affected: %d, expected: %d", tx.RowsAffected, g.rowsAffected)
 	}
 	return nil
 }
@@ -339,14 +820,14 @@ func (g *gDB) Delete(value any, conds ...any) error {
 func (g *gDB) Exec(sql string, values ...any) error {
 	if err := g.db.Exec(sql, values...).Error; err != nil {
 		g.logger.Error("db exec error", "sql", sql, "values", values, "error", err)
-		return errors.Wrap(err, "db exec error")
+		return errors.Wrap(classifyError(g.driver, err), "db exec error")
 	}
 	return nil
 }
 
 func (g *gDB) AutoMigrate(dst ...any) error {
 	return g.db.Transaction(func(tx *gorm.DB) error {
-		for key, value := range g.driver.MigrateOptions() {
+		for key, value := range migrateOptions(g.driver, *g.config) {
 			tx = tx.Set(key, value)
 		}
 		if err := tx.Migrator().AutoMigrate(dst...); err != nil {
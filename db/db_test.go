@@ -1,10 +1,13 @@
 package db_test
 
 import (
+	"context"
+	"database/sql"
 	"errors"
 	"testing"
 	"time"
 
+	"github.com/mattn/go-sqlite3"
 	"github.com/stretchr/testify/suite"
 	"gorm.io/gorm"
 
@@ -160,7 +163,8 @@ func (suite *DBTestSuite) TestGDB_Scopes() {
 	}
 	var model []*gorm.Model
 	suite.Require().NoError(suite.db.Scopes(moreThenOne, lessThree).Find(&model))
-	suite.T().Logf("%+v", model)
+	suite.Require().Len(model, 1)
+	suite.EqualValues(2, model[0].ID)
 
 	more := func(id []uint) func(db db.DB) db.DB {
 		return func(db db.DB) db.DB {
@@ -168,5 +172,299 @@ func (suite *DBTestSuite) TestGDB_Scopes() {
 		}
 	}
 	suite.Require().NoError(suite.db.Scopes(more([]uint{3, 4, 5})).Find(&model))
-	suite.T().Logf("%+v", model)
+	suite.Len(model, 3)
+}
+
+func (suite *DBTestSuite) TestReplicas() {
+	dir := suite.T().TempDir()
+	config := db.NewDefConfig()
+	config.Source = dir + "/primary.db"
+	config.Replicas = []string{dir + "/replica.db"}
+	config.EnableMetric = false
+
+	replicaConfig := db.NewDefConfig()
+	replicaConfig.Source = config.Replicas[0]
+	replicaConfig.EnableMetric = false
+	replica, err := db.NewDB(context.Background(), log.NewNopLogger(), replicaConfig)
+	suite.Require().NoError(err)
+	defer replica.Close()
+	suite.Require().NoError(replica.AutoMigrate(&gorm.Model{}))
+	suite.Require().NoError(replica.Create(&gorm.Model{ID: 1}))
+
+	primary, err := db.NewDB(context.Background(), log.NewNopLogger(), config)
+	suite.Require().NoError(err)
+	defer primary.Close()
+	suite.Require().NoError(primary.UsePrimary().AutoMigrate(&gorm.Model{}))
+	suite.Require().NoError(primary.Create(&gorm.Model{ID: 2}))
+
+	var models []gorm.Model
+	suite.Require().NoError(primary.Find(&models))
+	suite.Require().Len(models, 1)
+	suite.Equal(uint(1), models[0].ID)
+
+	var count int64
+	primary.Model(&gorm.Model{}).Where("id = ?", 2).Count(&count)
+	suite.Zero(count)
+
+	found, err := primary.UsePrimary().Where("id = ?", 2).First(&gorm.Model{})
+	suite.Require().NoError(err)
+	suite.True(found)
+
+	suite.Require().NoError(primary.Transaction(func(tx db.DB) error {
+		found, err = tx.Where("id = ?", 2).First(&gorm.Model{})
+		suite.Require().NoError(err)
+		suite.True(found)
+		return nil
+	}))
+
+	suite.Require().NoError(primary.Close())
+	var one int
+	suite.Require().ErrorContains(primary.Raw("select 1").Scan(&one), "sql: database is closed")
+	suite.Require().ErrorContains(primary.UsePrimary().Raw("select 1").Scan(&one), "sql: database is closed")
+}
+
+func (suite *DBTestSuite) TestFindInBatches() {
+	for i := 1; i <= 5; i++ {
+		suite.Require().NoError(suite.db.Create(&gorm.Model{ID: uint(i)}))
+	}
+
+	var ids []uint
+	var batches []gorm.Model
+	err := suite.db.FindInBatches(&batches, 2, func(tx db.DB, batch int) error {
+		suite.LessOrEqual(len(batches), 2)
+		for _, model := range batches {
+			ids = append(ids, model.ID)
+		}
+		return nil
+	})
+	suite.Require().NoError(err)
+	suite.Equal([]uint{1, 2, 3, 4, 5}, ids)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	err = suite.db.WithContext(ctx).FindInBatches(&batches, 2, func(tx db.DB, batch int) error {
+		cancel()
+		return nil
+	})
+	suite.Require().ErrorIs(err, context.Canceled)
+
+	err = suite.db.FindInBatches(&batches, 2, func(tx db.DB, batch int) error {
+		return errors.New("stop")
+	})
+	suite.Require().EqualError(err, "db find in batches error: stop")
+}
+
+func (suite *DBTestSuite) TestEach() {
+	for i := 1; i <= 5; i++ {
+		suite.Require().NoError(suite.db.Create(&gorm.Model{ID: uint(i)}))
+	}
+
+	var ids []uint
+	var model gorm.Model
+	err := suite.db.Model(&gorm.Model{}).Where("id > ?", 2).Order("id").Each(&model, func() error {
+		ids = append(ids, model.ID)
+		return nil
+	})
+	suite.Require().NoError(err)
+	suite.Equal([]uint{3, 4, 5}, ids)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	err = suite.db.WithContext(ctx).Model(&gorm.Model{}).Each(&model, func() error {
+		cancel()
+		return nil
+	})
+	suite.Require().ErrorIs(err, context.Canceled)
+
+	rows, err := suite.db.Model(&gorm.Model{}).Order("id desc").Rows()
+	suite.Require().NoError(err)
+	defer rows.Close()
+	suite.Require().True(rows.Next())
+	suite.Require().NoError(rows.Scan(&model))
+	suite.Equal(uint(5), model.ID)
+}
+
+type testAccount struct {
+	ID      uint   `gorm:"primarykey"`
+	Address string `gorm:"uniqueIndex"`
+	Balance int64
+	Nonce   uint64
+}
+
+func (suite *DBTestSuite) TestUpsert() {
+	suite.Require().NoError(suite.db.AutoMigrate(&testAccount{}))
+
+	account := &testAccount{Address: "0x1", Balance: 10, Nonce: 1}
+	suite.Require().NoError(suite.db.Upsert(account, []string{"address"}, []string{"balance"}))
+
+	suite.Require().NoError(suite.db.Upsert(&testAccount{Address: "0x1", Balance: 20, Nonce: 2}, []string{"address"}, []string{"balance"}))
+	var actual testAccount
+	suite.Require().NoError(suite.db.MustFirst(&actual, "address = ?", "0x1"))
+	suite.Equal(int64(20), actual.Balance)
+	suite.Equal(uint64(1), actual.Nonce)
+
+	accounts := []testAccount{{Address: "0x1", Balance: 30, Nonce: 3}, {Address: "0x2", Balance: 40, Nonce: 4}}
+	suite.Require().NoError(suite.db.Upsert(&accounts, []string{"address"}, nil))
+	var all []testAccount
+	suite.Require().NoError(suite.db.Order("address").Find(&all))
+	suite.Require().Len(all, 2)
+	suite.Equal(int64(30), all[0].Balance)
+	suite.Equal(uint64(3), all[0].Nonce)
+	suite.Equal(int64(40), all[1].Balance)
+
+	err := suite.db.RowsAffected(2).Upsert(&testAccount{Address: "0x3"}, []string{"address"}, nil)
+	suite.Require().EqualError(err, "db upsert error, rows affected: 1, expected: [2, 2]")
+}
+
+func (suite *DBTestSuite) TestCreateIgnore() {
+	suite.Require().NoError(suite.db.AutoMigrate(&testAccount{}))
+
+	created, err := suite.db.CreateIgnore(&testAccount{Address: "0x1", Balance: 10})
+	suite.Require().NoError(err)
+	suite.Equal(int64(1), created)
+
+	created, err = suite.db.CreateIgnore(&[]testAccount{{Address: "0x1", Balance: 20}, {Address: "0x2", Balance: 20}})
+	suite.Require().NoError(err)
+	suite.Equal(int64(1), created)
+
+	var actual testAccount
+	suite.Require().NoError(suite.db.MustFirst(&actual, "address = ?", "0x1"))
+	suite.Equal(int64(10), actual.Balance)
+
+	// only the inserted rows get an id, the skipped ones keep theirs unset
+	accounts := []testAccount{{Address: "0x1"}, {Address: "0x3"}, {Address: "0x2"}, {Address: "0x4"}}
+	created, err = suite.db.CreateIgnore(&accounts)
+	suite.Require().NoError(err)
+	suite.Equal(int64(2), created)
+	suite.Zero(accounts[0].ID)
+	suite.Zero(accounts[2].ID)
+	for _, account := range []testAccount{accounts[1], accounts[3]} {
+		var inserted testAccount
+		suite.Require().NoError(suite.db.MustFirst(&inserted, "address = ?", account.Address))
+		suite.Equal(inserted.ID, account.ID)
+	}
+
+	created, err = suite.db.CreateIgnore(&[]testAccount{{ID: accounts[1].ID, Address: "0x5"}, {ID: 100, Address: "0x6"}})
+	suite.Require().NoError(err)
+	suite.Equal(int64(1), created)
+}
+
+func (suite *DBTestSuite) TestCreateInBatches() {
+	// 20000 rows of 3 inserted columns exceed the sqlite placeholder limit in a single statement
+	models := make([]gorm.Model, 20000)
+	suite.Require().NoError(suite.db.CreateInBatches(&models, len(models)))
+	var count int64
+	suite.db.Model(&gorm.Model{}).Count(&count)
+	suite.Equal(int64(len(models)), count)
+
+	duplicates := []gorm.Model{{ID: 30001}, {ID: 30002}, {ID: 30003}, {ID: 1}}
+	err := suite.db.CreateInBatches(&duplicates, 2)
+	suite.Require().ErrorContains(err, "UNIQUE constraint failed")
+	found, err := suite.db.Where("id", 30001).First(&gorm.Model{})
+	suite.Require().NoError(err)
+	suite.False(found)
+
+	suite.Require().EqualError(suite.db.CreateInBatches(&gorm.Model{}, 10),
+		"db create in batches error, values must be a slice, got: *gorm.Model")
+}
+
+func (suite *DBTestSuite) TestTransactionWithOptions() {
+	opts := db.TxOptions{Isolation: sql.LevelSerializable}
+	suite.Require().NoError(suite.db.TransactionWithOptions(opts, func(tx db.DB) error {
+		found, err := tx.ForUpdate(true, false).Where("id", 1).First(&gorm.Model{})
+		suite.Require().NoError(err)
+		suite.False(found)
+		return tx.Create(&gorm.Model{ID: 1})
+	}))
+
+	// the connection of a timed out transaction is discarded together with its memory db
+	config := db.NewDefConfig()
+	config.Source = suite.T().TempDir() + "/tx.db"
+	config.EnableMetric = false
+	fileDB, err := db.NewDB(context.Background(), log.NewNopLogger(), config)
+	suite.Require().NoError(err)
+	defer fileDB.Close()
+	suite.Require().NoError(fileDB.AutoMigrate(&gorm.Model{}))
+	opts = db.TxOptions{Timeout: 10 * time.Millisecond}
+	err = fileDB.TransactionWithOptions(opts, func(tx db.DB) error {
+		time.Sleep(50 * time.Millisecond)
+		return tx.Create(&gorm.Model{ID: 2})
+	})
+	suite.Require().ErrorIs(err, context.DeadlineExceeded)
+	found, err := fileDB.Where("id", 2).First(&gorm.Model{})
+	suite.Require().NoError(err)
+	suite.False(found)
+
+	_, err = suite.db.ForUpdate(true, true).First(&gorm.Model{})
+	suite.Require().EqualError(err, "db first error: skip locked and no wait are mutually exclusive")
+}
+
+func (suite *DBTestSuite) TestTransactionRetry() {
+	config := db.NewDefConfig()
+	config.Source = "file:tx-retry.db?mode=memory"
+	config.EnableMetric = false
+	config.TxMaxRetries = 2
+	config.TxRetryBackoff = time.Millisecond
+	suite.Require().NoError(config.Validate())
+	retryDB, err := db.NewDB(context.Background(), log.NewNopLogger(), config)
+	suite.Require().NoError(err)
+	defer retryDB.Close()
+
+	attempts := 0
+	err = retryDB.Transaction(func(tx db.DB) error {
+		attempts++
+		if attempts < 3 {
+			return sqlite3.Error{Code: sqlite3.ErrBusy}
+		}
+		return nil
+	})
+	suite.Require().NoError(err)
+	suite.Equal(3, attempts)
+
+	attempts = 0
+	err = retryDB.TransactionWithOptions(db.TxOptions{}, func(tx db.DB) error {
+		attempts++
+		return sqlite3.Error{Code: sqlite3.ErrLocked}
+	})
+	suite.Require().Error(err)
+	suite.Equal(3, attempts)
+
+	attempts = 0
+	err = retryDB.Transaction(func(tx db.DB) error {
+		attempts++
+		return errors.New("rollback")
+	})
+	suite.Require().EqualError(err, "rollback")
+	suite.Equal(1, attempts)
+
+	// nested transactions are retried by the outermost one only
+	attempts = 0
+	err = retryDB.Transaction(func(tx db.DB) error {
+		return tx.Transaction(func(tx db.DB) error {
+			attempts++
+			return sqlite3.Error{Code: sqlite3.ErrBusy}
+		})
+	})
+	suite.Require().Error(err)
+	suite.Equal(3, attempts)
+}
+
+func (suite *DBTestSuite) TestErrors() {
+	suite.Require().NoError(suite.db.Create(&gorm.Model{ID: 1}))
+
+	err := suite.db.Create(&gorm.Model{ID: 1})
+	suite.Require().ErrorIs(err, db.ErrDuplicateKey)
+	suite.Require().EqualError(err, "db create error: UNIQUE constraint failed: model.id")
+
+	err = suite.db.MustFirst(&gorm.Model{}, "id = ?", 2)
+	suite.Require().ErrorIs(err, db.ErrNotFound)
+	suite.Require().ErrorIs(err, gorm.ErrRecordNotFound)
+	suite.Require().NoError(suite.db.MustFirst(&gorm.Model{}, "id = ?", 1))
+
+	err = suite.db.Model(&gorm.Model{}).Where("id = ?", 2).RowsAffected(1).Update("created_at", time.Now())
+	suite.Require().ErrorIs(err, db.ErrRowsAffectedMismatch)
+	suite.Require().EqualError(err, "db update error, rows affected: 0, expected: 1")
+
+	suite.Require().NoError(suite.db.Exec("PRAGMA foreign_keys = ON"))
+	suite.Require().NoError(suite.db.Exec("CREATE TABLE child (id INTEGER PRIMARY KEY, model_id INTEGER REFERENCES model(id))"))
+	err = suite.db.Exec("INSERT INTO child (id, model_id) VALUES (1, 100)")
+	suite.Require().ErrorIs(err, db.ErrForeignKey)
 }
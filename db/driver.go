@@ -7,6 +7,7 @@ import (
 	"github.com/golang-migrate/migrate/v4/source"
 	"github.com/pkg/errors"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 
 	"github.com/pundiai/go-sdk/log"
 )
@@ -42,6 +43,107 @@ type Driver interface {
 	MigrateOptions() map[string]string
 	GetMigrationsDriver() (source.Driver, error)
 	ToMigrateDriver(source string) (string, database.Driver, error)
+	// IsRetryableError reports whether a transaction failed with err can be re-run
+	IsRetryableError(err error) bool
+}
+
+// The optional interfaces below extend a Driver, each one falls back to a
+// default behavior when the driver doesn't implement it.
+
+// CredentialsDriver is implemented by the drivers whose sources carry credentials,
+// Config.User, Config.PasswordFile and file:// passwords require it and the
+// sources of the other drivers are masked by guessing their format.
+type CredentialsDriver interface {
+	// OpenResolved opens a source resolved again on every new connection, so
+	// that rotated credentials are picked up on reconnect
+	OpenResolved(resolve func() (string, error)) gorm.Dialector
+	// Credentials returns the user and password carried by source
+	Credentials(source string) (user, password string, err error)
+	// WithCredentials returns source carrying user and password instead of its own
+	WithCredentials(source, user, password string) (string, error)
+}
+
+// UpsertDriver reports the rows affected by an upsert, the default accepts
+// anything between 0 and twice the rows like mysql does.
+type UpsertDriver interface {
+	// UpsertRowsAffected returns the range of rows affected reported by the
+	// database when upserting the given number of rows
+	UpsertRowsAffected(rows int64) (minimum, maximum int64)
+}
+
+// PlaceholderDriver limits the bind parameters of one statement, the default is
+// defaultMaxPlaceholders.
+type PlaceholderDriver interface {
+	// MaxPlaceholders returns the maximum number of bind parameters in one statement
+	MaxPlaceholders() int
+}
+
+// LockingDriver builds the row locking clause, the default is the standard
+// FOR UPDATE [SKIP LOCKED | NOWAIT].
+type LockingDriver interface {
+	// ForUpdateClause returns the row locking clause of SELECT ... FOR UPDATE,
+	// or nil when the database has no row level locks
+	ForUpdateClause(skipLocked, noWait bool) clause.Expression
+}
+
+// ErrorClassifier translates native errors, by default none is classified.
+type ErrorClassifier interface {
+	// ClassifyError translates a native database error into ErrDuplicateKey or
+	// ErrForeignKey, it returns nil for any other error
+	ClassifyError(err error) error
+}
+
+// MigrateDriver derives the AutoMigrate options from the config, the default is
+// Driver.MigrateOptions.
+type MigrateDriver interface {
+	ConfigMigrateOptions(config Config) map[string]string
+}
+
+// ConnectDriver adds the connection options of the config to a resolved source,
+// by default the source connects as it is.
+type ConnectDriver interface {
+	// ConnectSource returns source with the connection options of config, the
+	// options set by source itself take precedence
+	ConnectSource(source string, config Config) string
+}
+
+// DryRunDriver builds the statements of the database without connecting to it,
+// NewRecorderDB requires it.
+type DryRunDriver interface {
+	// DryRunDialector returns a dialector whose initialization needs no server
+	DryRunDialector() gorm.Dialector
+}
+
+// defaultMaxPlaceholders is SQLITE_MAX_VARIABLE_NUMBER before sqlite 3.32.0, the
+// lowest limit of the common databases
+const defaultMaxPlaceholders = 999
+
+func migrateOptions(driver Driver, config Config) map[string]string {
+	if migrate, ok := driver.(MigrateDriver); ok {
+		return migrate.ConfigMigrateOptions(config)
+	}
+	return driver.MigrateOptions()
+}
+
+func upsertRowsAffected(driver Driver, rows int64) (minimum, maximum int64) {
+	if upsert, ok := driver.(UpsertDriver); ok {
+		return upsert.UpsertRowsAffected(rows)
+	}
+	return 0, rows * 2
+}
+
+func maxPlaceholders(driver Driver) int {
+	if placeholder, ok := driver.(PlaceholderDriver); ok {
+		return placeholder.MaxPlaceholders()
+	}
+	return defaultMaxPlaceholders
+}
+
+func forUpdateClause(driver Driver, skipLocked, noWait bool) clause.Expression {
+	if locking, ok := driver.(LockingDriver); ok {
+		return locking.ForUpdateClause(skipLocked, noWait)
+	}
+	return forUpdateLocking(skipLocked, noWait)
 }
 
 func RegisterMigrationsDriver(name string, driver source.Driver) {
@@ -59,3 +161,13 @@ func GetMigrationsDriver(name string) (source.Driver, error) {
 	}
 	return driver, nil
 }
+
+func forUpdateLocking(skipLocked, noWait bool) clause.Locking {
+	locking := clause.Locking{Strength: clause.LockingStrengthUpdate}
+	if skipLocked {
+		locking.Options = clause.LockingOptionsSkipLocked
+	} else if noWait {
+		locking.Options = clause.LockingOptionsNoWait
+	}
+	return locking
+}
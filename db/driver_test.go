@@ -4,11 +4,16 @@ import (
 	"fmt"
 	"testing"
 
+	"github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/mattn/go-sqlite3"
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm/clause"
 )
 
 func TestRegisterDriver(t *testing.T) {
-	assert.Len(t, drivers, 2)
+	assert.Len(t, drivers, 3)
 }
 
 func TestGetDriver(t *testing.T) {
@@ -30,6 +35,12 @@ func TestGetDriver(t *testing.T) {
 			want:    &Mysql{},
 			wantErr: assert.NoError,
 		},
+		{
+			name:    "test3",
+			driver:  PostgresDriver,
+			want:    &Postgres{},
+			wantErr: assert.NoError,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -41,3 +52,181 @@ func TestGetDriver(t *testing.T) {
 		})
 	}
 }
+
+func TestForUpdateClause(t *testing.T) {
+	tests := []struct {
+		name       string
+		driver     Driver
+		skipLocked bool
+		noWait     bool
+		want       clause.Expression
+	}{
+		{
+			name:   "sqlite",
+			driver: &Sqlite{},
+			want:   nil,
+		},
+		{
+			name:   "mysql",
+			driver: &Mysql{},
+			want:   clause.Locking{Strength: clause.LockingStrengthUpdate},
+		},
+		{
+			name:       "mysql skip locked",
+			driver:     &Mysql{},
+			skipLocked: true,
+			want:       clause.Locking{Strength: clause.LockingStrengthUpdate, Options: clause.LockingOptionsSkipLocked},
+		},
+		{
+			name:   "postgres no wait",
+			driver: &Postgres{},
+			noWait: true,
+			want:   clause.Locking{Strength: clause.LockingStrengthUpdate, Options: clause.LockingOptionsNoWait},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, forUpdateClause(tt.driver, tt.skipLocked, tt.noWait))
+		})
+	}
+}
+
+func TestIsRetryableError(t *testing.T) {
+	tests := []struct {
+		name   string
+		driver Driver
+		err    error
+		want   bool
+	}{
+		{
+			name:   "sqlite busy",
+			driver: &Sqlite{},
+			err:    errors.Wrap(sqlite3.Error{Code: sqlite3.ErrBusy}, "db create error"),
+			want:   true,
+		},
+		{
+			name:   "sqlite constraint",
+			driver: &Sqlite{},
+			err:    sqlite3.Error{Code: sqlite3.ErrConstraint},
+			want:   false,
+		},
+		{
+			name:   "mysql deadlock",
+			driver: &Mysql{},
+			err:    errors.Wrap(&mysql.MySQLError{Number: 1213}, "db updates error"),
+			want:   true,
+		},
+		{
+			name:   "mysql lock wait timeout",
+			driver: &Mysql{},
+			err:    &mysql.MySQLError{Number: 1205},
+			want:   true,
+		},
+		{
+			name:   "mysql duplicate entry",
+			driver: &Mysql{},
+			err:    &mysql.MySQLError{Number: 1062},
+			want:   false,
+		},
+		{
+			name:   "postgres serialization failure",
+			driver: &Postgres{},
+			err:    errors.Wrap(&pgconn.PgError{Code: "40001"}, "db exec error"),
+			want:   true,
+		},
+		{
+			name:   "other error",
+			driver: &Postgres{},
+			err:    errors.New("rollback"),
+			want:   false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.driver.IsRetryableError(tt.err))
+		})
+	}
+}
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		name   string
+		driver Driver
+		err    error
+		want   error
+	}{
+		{
+			name:   "sqlite unique",
+			driver: &Sqlite{},
+			err:    sqlite3.Error{Code: sqlite3.ErrConstraint, ExtendedCode: sqlite3.ErrConstraintUnique},
+			want:   ErrDuplicateKey,
+		},
+		{
+			name:   "sqlite foreign key",
+			driver: &Sqlite{},
+			err:    sqlite3.Error{Code: sqlite3.ErrConstraint, ExtendedCode: sqlite3.ErrConstraintForeignKey},
+			want:   ErrForeignKey,
+		},
+		{
+			name:   "mysql duplicate entry",
+			driver: &Mysql{},
+			err:    errors.Wrap(&mysql.MySQLError{Number: 1062}, "db create error"),
+			want:   ErrDuplicateKey,
+		},
+		{
+			name:   "mysql foreign key",
+			driver: &Mysql{},
+			err:    &mysql.MySQLError{Number: 1452},
+			want:   ErrForeignKey,
+		},
+		{
+			name:   "postgres unique violation",
+			driver: &Postgres{},
+			err:    &pgconn.PgError{Code: "23505"},
+			want:   ErrDuplicateKey,
+		},
+		{
+			name:   "postgres foreign key violation",
+			driver: &Postgres{},
+			err:    &pgconn.PgError{Code: "23503"},
+			want:   ErrForeignKey,
+		},
+		{
+			name:   "other error",
+			driver: &Mysql{},
+			err:    &mysql.MySQLError{Number: 1213},
+			want:   nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.driver.(ErrorClassifier).ClassifyError(tt.err))
+		})
+	}
+}
+
+// minimalDriver implements no optional interface, like a driver written before them
+type minimalDriver struct {
+	Driver
+}
+
+func TestOptionalDriverDefaults(t *testing.T) {
+	driver := minimalDriver{Driver: &Sqlite{}}
+	minimum, maximum := upsertRowsAffected(driver, 3)
+	assert.Equal(t, []int64{0, 6}, []int64{minimum, maximum})
+	assert.Equal(t, defaultMaxPlaceholders, maxPlaceholders(driver))
+	assert.Equal(t, forUpdateLocking(true, false), forUpdateClause(driver, true, false))
+
+	err := sqlite3.Error{Code: sqlite3.ErrConstraint, ExtendedCode: sqlite3.ErrConstraintUnique}
+	assert.Equal(t, error(err), classifyError(driver, err))
+
+	_, err2 := Config{User: "root"}.resolveSource(driver, "my.db")
+	assert.EqualError(t, err2, "driver does not support credentials")
+	assert.False(t, Config{PasswordFile: "password"}.hasPasswordFile(driver, "my.db"))
+	assert.Equal(t, "root:root@my.db", maskSource(driver, "root:root@my.db"))
+
+	config := Config{Sqlite: SqliteConfig{ForeignKeys: true}}
+	source, err2 := config.connectSource(driver, "my.db")
+	assert.NoError(t, err2)
+	assert.Equal(t, "my.db", source)
+}
@@ -0,0 +1,51 @@
+package db
+
+import (
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// Sentinel errors returned by DB, match them with errors.Is.
+var (
+	ErrNotFound             = errors.New("record not found")
+	ErrDuplicateKey         = errors.New("duplicate key")
+	ErrForeignKey           = errors.New("foreign key violation")
+	ErrRowsAffectedMismatch = errors.New("rows affected mismatch")
+	ErrInvalidCursor        = errors.New("invalid cursor")
+	// ErrStaleVersion is returned when an update of a model embedding model.Versioned
+	// matches no row, because it was updated by someone else since it was read
+	ErrStaleVersion = errors.New("stale version")
+)
+
+// classifiedError tags a driver error with one of the sentinel errors,
+// keeping the original message and error chain intact.
+type classifiedError struct {
+	kind error
+	err  error
+}
+
+func (e *classifiedError) Error() string {
+	return e.err.Error()
+}
+
+func (e *classifiedError) Unwrap() []error {
+	return []error{e.kind, e.err}
+}
+
+func classifyError(driver Driver, err error) error {
+	var kind error
+	if classifier, ok := driver.(ErrorClassifier); ok {
+		kind = classifier.ClassifyError(err)
+	}
+	if kind == nil && errors.Is(err, gorm.ErrRecordNotFound) {
+		kind = ErrNotFound
+	}
+	if kind == nil {
+		return err
+	}
+	return &classifiedError{kind: kind, err: err}
+}
+
+func rowsAffectedError(format string, args ...any) error {
+	return &classifiedError{kind: ErrRowsAffectedMismatch, err: errors.Errorf(format, args...)}
+}
@@ -0,0 +1,135 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/mattn/go-sqlite3"
+	"github.com/pkg/errors"
+
+	"github.com/pundiai/go-sdk/tool"
+)
+
+const fixtureBatchSize = 500
+
+// LoadFixture decodes a .json, .yaml or .yml file into dest, a pointer to a
+// slice of models, and inserts its rows. YAML keys follow the json tags.
+func LoadFixture(database DB, path string, dest any) error {
+	value := reflect.ValueOf(dest)
+	if value.Kind() != reflect.Pointer || value.Elem().Kind() != reflect.Slice {
+		return errors.New("fixture dest must be a pointer to slice")
+	}
+	var err error
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		err = tool.LoadJSONFile(path, dest)
+	case ".yaml", ".yml":
+		err = tool.LoadYAMLFile(path, dest)
+	default:
+		return errors.Errorf("fixture %s is neither json nor yaml", path)
+	}
+	if err != nil {
+		return errors.Wrapf(err, "load fixture %s error", path)
+	}
+	if value.Elem().Len() == 0 {
+		return nil
+	}
+	return errors.WithMessagef(database.CreateInBatches(dest, fixtureBatchSize), "insert fixture %s error", path)
+}
+
+// Snapshot is a copy of a sqlite database kept in memory, taken and restored
+// with the sqlite online backup API instead of re-inserting the rows.
+type Snapshot struct {
+	db *sql.DB
+}
+
+// TakeSnapshot copies the current state of a sqlite database, such as the one of
+// NewMemoryDB. An in-memory database lives in its connection, so the snapshot is
+// taken from, and later restored to, the connection the pool hands out.
+func TakeSnapshot(ctx context.Context, database DB) (*Snapshot, error) {
+	source, err := sqliteSQLDB(database)
+	if err != nil {
+		return nil, err
+	}
+	store, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		return nil, errors.Wrap(err, "open snapshot error")
+	}
+	// the snapshot lives as long as its only connection
+	store.SetMaxOpenConns(1)
+	store.SetMaxIdleConns(1)
+	store.SetConnMaxLifetime(0)
+	store.SetConnMaxIdleTime(0)
+	if err = backupSQLite(ctx, store, source); err != nil {
+		_ = store.Close()
+		return nil, errors.WithMessage(err, "take snapshot error")
+	}
+	return &Snapshot{db: store}, nil
+}
+
+// Restore overwrites database with the snapshot, the snapshot can be restored again
+func (s *Snapshot) Restore(ctx context.Context, database DB) error {
+	target, err := sqliteSQLDB(database)
+	if err != nil {
+		return err
+	}
+	return errors.WithMessage(backupSQLite(ctx, target, s.db), "restore snapshot error")
+}
+
+func (s *Snapshot) Close() error {
+	return s.db.Close()
+}
+
+func sqliteSQLDB(database DB) (*sql.DB, error) {
+	g, ok := database.(*gDB)
+	if !ok {
+		return nil, errors.Errorf("snapshot of %T is not supported", database)
+	}
+	if _, ok = g.driver.(*Sqlite); !ok {
+		return nil, errors.New("snapshot is only supported by sqlite")
+	}
+	sqlDB, err := g.db.DB()
+	if err != nil {
+		return nil, errors.Wrap(err, "get sql db error")
+	}
+	return sqlDB, nil
+}
+
+// backupSQLite copies the main database of source into target
+func backupSQLite(ctx context.Context, target, source *sql.DB) error {
+	targetConn, err := target.Conn(ctx)
+	if err != nil {
+		return errors.Wrap(err, "get target conn error")
+	}
+	defer func() { _ = targetConn.Close() }()
+	sourceConn, err := source.Conn(ctx)
+	if err != nil {
+		return errors.Wrap(err, "get source conn error")
+	}
+	defer func() { _ = sourceConn.Close() }()
+
+	return targetConn.Raw(func(targetDriverConn any) error {
+		return sourceConn.Raw(func(sourceDriverConn any) error {
+			targetSQLite, ok := targetDriverConn.(*sqlite3.SQLiteConn)
+			if !ok {
+				return errors.Errorf("unexpected target conn %T", targetDriverConn)
+			}
+			sourceSQLite, ok := sourceDriverConn.(*sqlite3.SQLiteConn)
+			if !ok {
+				return errors.Errorf("unexpected source conn %T", sourceDriverConn)
+			}
+			backup, err := targetSQLite.Backup("main", sourceSQLite, "main")
+			if err != nil {
+				return errors.Wrap(err, "sqlite backup error")
+			}
+			if _, err = backup.Step(-1); err != nil {
+				_ = backup.Finish()
+				return errors.Wrap(err, "sqlite backup step error")
+			}
+			return errors.Wrap(backup.Finish(), "sqlite backup finish error")
+		})
+	})
+}
@@ -0,0 +1,46 @@
+package db_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pundiai/go-sdk/db"
+	"github.com/pundiai/go-sdk/log"
+)
+
+func TestFixtures(t *testing.T) {
+	testDB := db.NewMemoryDB(log.LevelError, "fixtures")
+	require.NoError(t, testDB.AutoMigrate(&pageModel{}))
+
+	var yamlModels, jsonModels []pageModel
+	require.NoError(t, db.LoadFixture(testDB, "testdata/fixture.yaml", &yamlModels))
+	require.NoError(t, db.LoadFixture(testDB, "testdata/fixture.json", &jsonModels))
+	require.Len(t, yamlModels, 2)
+	assert.Equal(t, time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC), yamlModels[1].CreatedAt.UTC())
+	require.ErrorContains(t, db.LoadFixture(testDB, "testdata/fixture.txt", &jsonModels), "neither json nor yaml")
+
+	ctx := context.Background()
+	snapshot, err := db.TakeSnapshot(ctx, testDB)
+	require.NoError(t, err)
+	defer func() { require.NoError(t, snapshot.Close()) }()
+
+	for i := 0; i < 2; i++ {
+		require.NoError(t, testDB.Where("id > ?", 1).Delete(&pageModel{}))
+		require.NoError(t, testDB.Create(&pageModel{}))
+		var count int64
+		testDB.Model(&pageModel{}).Count(&count)
+		assert.EqualValues(t, 2, count)
+
+		require.NoError(t, snapshot.Restore(ctx, testDB))
+		var models []pageModel
+		require.NoError(t, testDB.Find(&models))
+		assert.Equal(t, []uint{1, 2, 3}, pageIDs(models))
+	}
+
+	_, err = db.TakeSnapshot(ctx, &struct{ db.DB }{testDB})
+	require.Error(t, err)
+}
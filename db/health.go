@@ -0,0 +1,123 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/pundiai/go-sdk/server"
+)
+
+var _ server.HealthChecker = (*HealthChecker)(nil)
+
+// HealthConfig configures the HealthChecker, a zero threshold disables the check.
+type HealthConfig struct {
+	// Timeout bounds the ping to the database
+	Timeout time.Duration `yaml:"timeout" mapstructure:"timeout"`
+	// DegradedWaitCount and UnhealthyWaitCount bound the connections waited for since the last check
+	DegradedWaitCount  int64 `yaml:"degraded_wait_count" mapstructure:"degraded_wait_count"`
+	UnhealthyWaitCount int64 `yaml:"unhealthy_wait_count" mapstructure:"unhealthy_wait_count"`
+	// DegradedInUse and UnhealthyInUse bound the connections currently in use
+	DegradedInUse  int `yaml:"degraded_in_use" mapstructure:"degraded_in_use"`
+	UnhealthyInUse int `yaml:"unhealthy_in_use" mapstructure:"unhealthy_in_use"`
+}
+
+func NewDefHealthConfig() HealthConfig {
+	return HealthConfig{
+		Timeout: 3 * time.Second,
+	}
+}
+
+func (c HealthConfig) Validate() error {
+	if c.Timeout <= 0 {
+		return errors.New("timeout is invalid, must greater than 0")
+	}
+	if c.DegradedWaitCount < 0 || c.UnhealthyWaitCount < 0 || c.DegradedInUse < 0 || c.UnhealthyInUse < 0 {
+		return errors.New("thresholds are invalid, must not be negative")
+	}
+	if c.DegradedWaitCount > 0 && c.UnhealthyWaitCount > 0 && c.DegradedWaitCount > c.UnhealthyWaitCount {
+		return errors.New("degraded_wait_count must not greater than unhealthy_wait_count")
+	}
+	if c.DegradedInUse > 0 && c.UnhealthyInUse > 0 && c.DegradedInUse > c.UnhealthyInUse {
+		return errors.New("degraded_in_use must not greater than unhealthy_in_use")
+	}
+	return nil
+}
+
+// HealthChecker reports whether the database is reachable and how loaded its
+// connection pool is, it plugs into server.NewHealthHandler.
+type HealthChecker struct {
+	db     DB
+	config HealthConfig
+	name   string
+
+	mu            sync.Mutex
+	lastWaitCount int64
+}
+
+// NewHealthChecker names the check db:<database>, a DB not opened by NewDB has
+// no config to name its database from and is named db.
+func NewHealthChecker(db DB, config HealthConfig) *HealthChecker {
+	name := "db"
+	if g, ok := db.(*gDB); ok {
+		name += ":" + nameDatabase(g.driver, *g.config)
+	}
+	return &HealthChecker{
+		db:            db,
+		config:        config,
+		name:          name,
+		lastWaitCount: db.Stats().WaitCount,
+	}
+}
+
+func (h *HealthChecker) Name() string {
+	return h.name
+}
+
+func (h *HealthChecker) CheckHealth(ctx context.Context) server.HealthCheck {
+	ctx, cancel := context.WithTimeout(ctx, h.config.Timeout)
+	defer cancel()
+	if err := h.db.Ping(ctx); err != nil {
+		return server.HealthCheck{Status: server.HealthStatusUnhealthy, Message: err.Error()}
+	}
+
+	stats := h.db.Stats()
+	h.mu.Lock()
+	waitCount := stats.WaitCount - h.lastWaitCount
+	h.lastWaitCount = stats.WaitCount
+	h.mu.Unlock()
+
+	check := server.HealthCheck{
+		Status: server.HealthStatusHealthy,
+		Details: map[string]any{
+			"open_connections": stats.OpenConnections,
+			"in_use":           stats.InUse,
+			"idle":             stats.Idle,
+			"wait_count":       waitCount,
+			"wait_duration":    stats.WaitDuration.String(),
+		},
+	}
+	switch {
+	case reaches(waitCount, h.config.UnhealthyWaitCount):
+		check.Status = server.HealthStatusUnhealthy
+		check.Message = fmt.Sprintf("wait count %d reaches %d", waitCount, h.config.UnhealthyWaitCount)
+	case reaches(int64(stats.InUse), int64(h.config.UnhealthyInUse)):
+		check.Status = server.HealthStatusUnhealthy
+		check.Message = fmt.Sprintf("in use connections %d reaches %d", stats.InUse, h.config.UnhealthyInUse)
+	case reaches(waitCount, h.config.DegradedWaitCount):
+		check.Status = server.HealthStatusDegraded
+		check.Message = fmt.Sprintf("wait count %d reaches %d", waitCount, h.config.DegradedWaitCount)
+	case reaches(int64(stats.InUse), int64(h.config.DegradedInUse)):
+		check.Status = server.HealthStatusDegraded
+		check.Message = fmt.Sprintf("in use connections %d reaches %d", stats.InUse, h.config.DegradedInUse)
+	}
+	return check
+}
+
+// reaches reports whether value reaches threshold, a zero threshold is disabled
+func reaches(value, threshold int64) bool {
+	return threshold > 0 && value >= threshold
+}
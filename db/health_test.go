@@ -0,0 +1,93 @@
+package db_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pundiai/go-sdk/db"
+	"github.com/pundiai/go-sdk/log"
+	"github.com/pundiai/go-sdk/server"
+)
+
+func TestPingAndStats(t *testing.T) {
+	testDB := db.NewMemoryDB(log.LevelError, "health-ping")
+	require.NoError(t, testDB.Ping(context.Background()))
+	assert.Equal(t, db.NewDefConfig().MaxOpenConn, testDB.Stats().MaxOpenConnections)
+
+	tx := testDB.Begin()
+	assert.Equal(t, 1, testDB.Stats().InUse)
+	require.NoError(t, tx.Rollback())
+	assert.Equal(t, 0, testDB.Stats().InUse)
+
+	require.NoError(t, testDB.Close())
+	require.ErrorContains(t, testDB.Ping(context.Background()), "db ping error")
+}
+
+func TestHealthChecker_UnnamedSource(t *testing.T) {
+	config := db.NewDefConfig()
+	config.Source = ":memory:"
+	config.EnableMetric = false
+	testDB, err := db.NewDB(context.Background(), log.NewNopLogger(), config)
+	require.NoError(t, err)
+	defer testDB.Close()
+
+	checker := db.NewHealthChecker(testDB, db.NewDefHealthConfig())
+	assert.Equal(t, "db:sqlite", checker.Name())
+	assert.Equal(t, server.HealthStatusHealthy, checker.CheckHealth(context.Background()).Status)
+}
+
+func TestHealthChecker(t *testing.T) {
+	testDB := db.NewMemoryDB(log.LevelError, "health-checker")
+	config := db.NewDefHealthConfig()
+	config.DegradedInUse = 1
+	config.UnhealthyInUse = 2
+	require.NoError(t, config.Validate())
+	checker := db.NewHealthChecker(testDB, config)
+	assert.Equal(t, "db:health-checker", checker.Name())
+
+	check := checker.CheckHealth(context.Background())
+	assert.Equal(t, server.HealthStatusHealthy, check.Status)
+	assert.Equal(t, 0, check.Details["in_use"])
+
+	tx1 := testDB.Begin()
+	check = checker.CheckHealth(context.Background())
+	assert.Equal(t, server.HealthStatusDegraded, check.Status)
+	assert.Equal(t, "in use connections 1 reaches 1", check.Message)
+
+	tx2 := testDB.Begin()
+	check = checker.CheckHealth(context.Background())
+	assert.Equal(t, server.HealthStatusUnhealthy, check.Status)
+	require.NoError(t, tx1.Rollback())
+	require.NoError(t, tx2.Rollback())
+
+	recorder := httptest.NewRecorder()
+	server.NewHealthHandler(checker).ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/health", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	var report server.HealthReport
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &report))
+	assert.Equal(t, server.HealthStatusHealthy, report.Status)
+
+	require.NoError(t, testDB.Close())
+	recorder = httptest.NewRecorder()
+	server.NewHealthHandler(checker).ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/health", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &report))
+	assert.Equal(t, server.HealthStatusUnhealthy, report.Checks["db:health-checker"].Status)
+}
+
+func TestHealthConfig_Validate(t *testing.T) {
+	config := db.NewDefHealthConfig()
+	require.NoError(t, config.Validate())
+	config.DegradedWaitCount = 10
+	config.UnhealthyWaitCount = 5
+	require.Error(t, config.Validate())
+	config = db.NewDefHealthConfig()
+	config.Timeout = 0
+	require.Error(t, config.Validate())
+}
@@ -0,0 +1,117 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/pundiai/go-sdk/log"
+)
+
+var (
+	_                      logger.Interface  = (*gormLogger)(nil)
+	_                      gorm.ParamsFilter = (*gormLogger)(nil)
+	callerSkipFuncPrefixes                   = []string{"gorm.io/", "github.com/pundiai/go-sdk/db.", "github.com/pundiai/go-sdk/dao."}
+)
+
+// gormLogger adapts log.Logger to the gorm logger, so that sql logs share the
+// structured output of the sdk logger.
+type gormLogger struct {
+	logger               log.Logger
+	level                logger.LogLevel
+	slowThreshold        time.Duration
+	parameterizedQueries bool
+}
+
+// NewGormLogger returns a gorm logger writing sql logs to l, with the log level,
+// slow threshold and parameterized queries of config.
+func NewGormLogger(l log.Logger, config Config) logger.Interface {
+	return &gormLogger{
+		logger:               l,
+		level:                config.GetLogLevel(),
+		slowThreshold:        config.SlowThreshold,
+		parameterizedQueries: config.ParameterizedQueries,
+	}
+}
+
+func (l *gormLogger) LogMode(level logger.LogLevel) logger.Interface {
+	newLogger := *l
+	newLogger.level = level
+	return &newLogger
+}
+
+func (l *gormLogger) Info(_ context.Context, msg string, data ...any) {
+	if l.level >= logger.Info {
+		l.logger.Info(fmt.Sprintf(msg, data...), "caller", fileWithLineNum())
+	}
+}
+
+func (l *gormLogger) Warn(_ context.Context, msg string, data ...any) {
+	if l.level >= logger.Warn {
+		l.logger.Warn(fmt.Sprintf(msg, data...), "caller", fileWithLineNum())
+	}
+}
+
+func (l *gormLogger) Error(_ context.Context, msg string, data ...any) {
+	if l.level >= logger.Error {
+		l.logger.Error(fmt.Sprintf(msg, data...), "caller", fileWithLineNum())
+	}
+}
+
+func (l *gormLogger) Trace(_ context.Context, begin time.Time, fc func() (sql string, rowsAffected int64), err error) {
+	if l.level <= logger.Silent {
+		return
+	}
+	elapsed := time.Since(begin)
+	switch {
+	case err != nil && l.level >= logger.Error && !errors.Is(err, gorm.ErrRecordNotFound):
+		sql, rows := fc()
+		l.logger.Error("db sql error", "sql", sql, "rows", rows, "elapsed", elapsed, "caller", fileWithLineNum(), "error", err)
+	case l.slowThreshold > 0 && elapsed > l.slowThreshold && l.level >= logger.Warn:
+		sql, rows := fc()
+		l.logger.Warn("db slow sql", "sql", sql, "rows", rows, "elapsed", elapsed, "caller", fileWithLineNum(),
+			"slow threshold", l.slowThreshold)
+	case l.level >= logger.Info:
+		sql, rows := fc()
+		l.logger.Info("db sql", "sql", sql, "rows", rows, "elapsed", elapsed, "caller", fileWithLineNum())
+	}
+}
+
+// ParamsFilter drops the bound variables from the logged sql when parameterized queries is enabled
+func (l *gormLogger) ParamsFilter(_ context.Context, sql string, params ...any) (string, []any) {
+	if l.parameterizedQueries {
+		return sql, nil
+	}
+	return sql, params
+}
+
+// fileWithLineNum returns the first caller outside of gorm and the sdk db wrappers
+func fileWithLineNum() string {
+	pcs := [16]uintptr{}
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(3, pcs[:])])
+	for {
+		frame, more := frames.Next()
+		if !hasAnyPrefix(frame.Function, callerSkipFuncPrefixes) {
+			return frame.File + ":" + strconv.Itoa(frame.Line)
+		}
+		if !more {
+			return ""
+		}
+	}
+}
+
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(s, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,72 @@
+package db_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/pundiai/go-sdk/db"
+	"github.com/pundiai/go-sdk/log"
+)
+
+func newBufferLogger(t *testing.T, output *bytes.Buffer) log.Logger {
+	t.Helper()
+	defaultWriter := log.DefaultWriter
+	defer func() { log.DefaultWriter = defaultWriter }()
+	log.DefaultWriter = output
+	logger, err := log.NewZeroLogger(log.FormatJSON, log.LevelInfo)
+	require.NoError(t, err)
+	return logger
+}
+
+func TestGormLogger(t *testing.T) {
+	output := new(bytes.Buffer)
+	config := db.NewDefConfig()
+	config.Source = "file:gorm-logger.db?mode=memory"
+	config.LogLevel = db.LogLevelInfo
+	config.ParameterizedQueries = true
+	config.EnableMetric = false
+	testDB, err := db.NewDB(context.Background(), newBufferLogger(t, output), config)
+	require.NoError(t, err)
+	defer testDB.Close()
+
+	require.NoError(t, testDB.AutoMigrate(&gorm.Model{}))
+	output.Reset()
+	require.NoError(t, testDB.Create(&gorm.Model{ID: 100}))
+	assert.Contains(t, output.String(), `"message":"db sql"`)
+	assert.Contains(t, output.String(), `"sql":"INSERT INTO`)
+	assert.Contains(t, output.String(), `"rows":1`)
+	assert.Contains(t, output.String(), `"caller":"`)
+	assert.Contains(t, output.String(), `logger_test.go:`)
+	assert.NotContains(t, output.String(), "100")
+
+	// WithLogger swaps the sql logger too
+	otherOutput := new(bytes.Buffer)
+	output.Reset()
+	_, err = testDB.WithLogger(newBufferLogger(t, otherOutput)).Where("id = ?", 100).First(&gorm.Model{})
+	require.NoError(t, err)
+	assert.Empty(t, output.String())
+	assert.Contains(t, otherOutput.String(), `"sql":"SELECT * FROM`)
+}
+
+func TestGormLogger_SlowThreshold(t *testing.T) {
+	output := new(bytes.Buffer)
+	config := db.NewDefConfig()
+	config.Source = "file:gorm-logger-slow.db?mode=memory"
+	config.LogLevel = db.LogLevelWarn
+	config.SlowThreshold = time.Nanosecond
+	config.EnableMetric = false
+	testDB, err := db.NewDB(context.Background(), newBufferLogger(t, output), config)
+	require.NoError(t, err)
+	defer testDB.Close()
+
+	require.NoError(t, testDB.AutoMigrate(&gorm.Model{}))
+	require.NoError(t, testDB.Create(&gorm.Model{ID: 100}))
+	assert.Contains(t, output.String(), `"message":"db slow sql"`)
+	assert.Contains(t, output.String(), `NULL,100)`)
+}
@@ -0,0 +1,151 @@
+package db
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/pundiai/go-sdk/log"
+	"github.com/pundiai/go-sdk/server"
+)
+
+var _ server.Server = (*Manager)(nil)
+
+// Manager holds the databases of a service by name, each one is opened on its
+// first use and all of them are closed with the server.
+type Manager struct {
+	logger  log.Logger
+	configs map[string]Config
+	// ctx is canceled by Close, stopping the databases still waiting to connect
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu     sync.Mutex
+	dbs    map[string]*managedDB
+	closed bool
+}
+
+// managedDB is opened once by the first Open of its name, the concurrent calls
+// wait for done. A failed open is forgotten so that the next Open tries again.
+type managedDB struct {
+	done chan struct{}
+	db   DB
+	err  error
+}
+
+// NewManager validates every config up front, no database is opened yet.
+func NewManager(logger log.Logger, configs map[string]Config) (*Manager, error) {
+	for _, name := range sortedNames(configs) {
+		if err := configs[name].Validate(); err != nil {
+			return nil, errors.WithMessagef(err, "db %s config is invalid", name)
+		}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Manager{
+		logger:  logger.With("server", "db"),
+		configs: configs,
+		ctx:     ctx,
+		cancel:  cancel,
+		dbs:     make(map[string]*managedDB, len(configs)),
+	}, nil
+}
+
+// Open returns the database of name, opening it on the first call. The lock is
+// not held while connecting, which may retry for ConnectRetry.MaxWait.
+func (m *Manager) Open(name string) (DB, error) {
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		return nil, errors.New("db manager is closed")
+	}
+	if managed, ok := m.dbs[name]; ok {
+		m.mu.Unlock()
+		<-managed.done
+		return managed.db, managed.err
+	}
+	config, ok := m.configs[name]
+	if !ok {
+		m.mu.Unlock()
+		return nil, errors.Errorf("db %s is not configured", name)
+	}
+	managed := &managedDB{done: make(chan struct{})}
+	m.dbs[name] = managed
+	m.mu.Unlock()
+
+	db, err := NewDB(m.ctx, m.logger.With("db", name), config)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	defer close(managed.done)
+	if err != nil {
+		delete(m.dbs, name)
+		managed.err = errors.WithMessagef(err, "open db %s error", name)
+		return nil, managed.err
+	}
+	if m.closed {
+		_ = db.Close()
+		managed.err = errors.New("db manager is closed")
+		return nil, managed.err
+	}
+	m.logger.Info("open db success", "db", name, "config", config.String())
+	managed.db = db
+	return db, nil
+}
+
+// Get is Open for the names known to be configured, it panics on any error.
+func (m *Manager) Get(name string) DB {
+	db, err := m.Open(name)
+	if err != nil {
+		panic(err)
+	}
+	return db
+}
+
+// Names returns the configured database names in order
+func (m *Manager) Names() []string {
+	return sortedNames(m.configs)
+}
+
+func (*Manager) Start(context.Context, *errgroup.Group) error {
+	return nil
+}
+
+// Close closes every opened database and stops the ones still connecting, the
+// manager cannot be used afterward.
+func (m *Manager) Close() error {
+	m.mu.Lock()
+	m.closed = true
+	m.cancel()
+	dbs := make(map[string]DB, len(m.dbs))
+	for name, managed := range m.dbs {
+		// the databases still connecting are closed by their Open
+		if managed.db != nil {
+			dbs[name] = managed.db
+		}
+	}
+	m.dbs = make(map[string]*managedDB)
+	m.mu.Unlock()
+
+	var closeErr error
+	for _, name := range sortedNames(dbs) {
+		if err := dbs[name].Close(); err != nil {
+			m.logger.Error("close db error", "db", name, "error", err)
+			if closeErr == nil {
+				closeErr = errors.Wrapf(err, "close db %s error", name)
+			}
+		}
+	}
+	return closeErr
+}
+
+func sortedNames[T any](m map[string]T) []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,127 @@
+package db_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/pundiai/go-sdk/db"
+	"github.com/pundiai/go-sdk/log"
+)
+
+func TestManager(t *testing.T) {
+	dir := t.TempDir()
+	newConfig := func(name string) db.Config {
+		config := db.NewDefConfig()
+		config.Source = filepath.Join(dir, name+".db")
+		config.EnableMetric = false
+		return config
+	}
+	manager, err := db.NewManager(log.NewNopLogger(), map[string]db.Config{
+		"indexer":  newConfig("indexer"),
+		"accounts": newConfig("accounts"),
+	})
+	require.NoError(t, err)
+	require.NoError(t, manager.Start(context.Background(), new(errgroup.Group)))
+	assert.Equal(t, []string{"accounts", "indexer"}, manager.Names())
+
+	indexer := manager.Get("indexer")
+	assert.Same(t, indexer, manager.Get("indexer"))
+	assert.Equal(t, "indexer", indexer.GetDriver().GetDatabaseName(indexer.GetSource()))
+	accounts, err := manager.Open("accounts")
+	require.NoError(t, err)
+	require.NoError(t, accounts.Ping(context.Background()))
+
+	_, err = manager.Open("unknown")
+	require.EqualError(t, err, "db unknown is not configured")
+	assert.Panics(t, func() { manager.Get("unknown") })
+
+	require.NoError(t, manager.Close())
+	require.Error(t, indexer.Ping(context.Background()))
+	_, err = manager.Open("indexer")
+	require.EqualError(t, err, "db manager is closed")
+}
+
+func TestManager_OpenWhileConnecting(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	port := listener.Addr().(*net.TCPAddr).Port
+	require.NoError(t, listener.Close())
+
+	waiting := db.NewDefConfig()
+	waiting.Driver = db.MysqlDriver
+	waiting.Source = "root:root@tcp(127.0.0.1:" + strconv.Itoa(port) + ")/my"
+	waiting.EnableMetric = false
+	waiting.ConnectRetry = db.ConnectRetryConfig{MaxWait: time.Minute, Backoff: 10 * time.Millisecond, MaxBackoff: 50 * time.Millisecond}
+	ready := db.NewDefConfig()
+	ready.Source = filepath.Join(t.TempDir(), "ready.db")
+	ready.EnableMetric = false
+	manager, err := db.NewManager(log.NewNopLogger(), map[string]db.Config{"waiting": waiting, "ready": ready})
+	require.NoError(t, err)
+
+	opened := make(chan error, 2)
+	for range 2 {
+		go func() {
+			_, err := manager.Open("waiting")
+			opened <- err
+		}()
+	}
+	time.Sleep(50 * time.Millisecond)
+	require.NoError(t, manager.Get("ready").Ping(context.Background()))
+
+	start := time.Now()
+	require.NoError(t, manager.Close())
+	for range 2 {
+		select {
+		case err = <-opened:
+			require.Error(t, err)
+		case <-time.After(5 * time.Second):
+			t.Fatal("open is not stopped by close")
+		}
+	}
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
+
+func TestManager_OpenParallel(t *testing.T) {
+	dir := t.TempDir()
+	configs := make(map[string]db.Config)
+	for i := range 8 {
+		config := db.NewDefConfig()
+		config.Source = filepath.Join(dir, "parallel"+strconv.Itoa(i)+".db")
+		config.EnableMetric = false
+		configs["parallel"+strconv.Itoa(i)] = config
+	}
+	manager, err := db.NewManager(log.NewNopLogger(), configs)
+	require.NoError(t, err)
+	defer manager.Close()
+
+	group := new(errgroup.Group)
+	for _, name := range manager.Names() {
+		group.Go(func() error {
+			database, err := manager.Open(name)
+			if err != nil {
+				return err
+			}
+			if database.GetDriver().GetDatabaseName(database.GetSource()) != name {
+				return errors.New("unexpected database name of " + name)
+			}
+			return nil
+		})
+	}
+	require.NoError(t, group.Wait())
+}
+
+func TestNewManager_Validate(t *testing.T) {
+	config := db.NewDefConfig()
+	config.MaxOpenConn = 0
+	_, err := db.NewManager(log.NewNopLogger(), map[string]db.Config{"indexer": config})
+	require.EqualError(t, err, "db indexer config is invalid: max_open_conn is invalid, must between 1 and 500")
+}
@@ -0,0 +1,100 @@
+package db
+
+import (
+	"time"
+
+	"github.com/armon/go-metrics"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+
+	"github.com/pundiai/go-sdk/telemetry"
+)
+
+const (
+	metricsPluginName = "sdk:statement_metrics"
+	metricsStartKey   = "sdk:statement_metrics:start"
+
+	MetricLabelNameDatabase  = "database"
+	MetricLabelNameOperation = "operation"
+	MetricLabelNameTable     = "table"
+	MetricLabelNameOutcome   = "outcome"
+
+	MetricOutcomeSuccess = "success"
+	MetricOutcomeError   = "error"
+)
+
+var (
+	MetricKeyStatementLatency = []string{"db", "statement", "latency"}
+	MetricKeyStatementErrors  = []string{"db", "statement", "errors"}
+)
+
+var _ gorm.Plugin = (*metricsPlugin)(nil)
+
+// metricsPlugin records the latency and errors of every statement through the
+// telemetry package, labelled by operation, table and outcome.
+type metricsPlugin struct {
+	database string
+}
+
+func newMetricsPlugin(database string) *metricsPlugin {
+	return &metricsPlugin{database: database}
+}
+
+func (*metricsPlugin) Name() string {
+	return metricsPluginName
+}
+
+func (p *metricsPlugin) Initialize(db *gorm.DB) error {
+	callback := db.Callback()
+	errs := []error{
+		callback.Create().Before("gorm:create").Register(metricsPluginName+":before_create", p.before),
+		callback.Create().After("gorm:create").Register(metricsPluginName+":after_create", p.after("create")),
+		callback.Query().Before("gorm:query").Register(metricsPluginName+":before_query", p.before),
+		callback.Query().After("gorm:query").Register(metricsPluginName+":after_query", p.after("query")),
+		callback.Update().Before("gorm:update").Register(metricsPluginName+":before_update", p.before),
+		callback.Update().After("gorm:update").Register(metricsPluginName+":after_update", p.after("update")),
+		callback.Delete().Before("gorm:delete").Register(metricsPluginName+":before_delete", p.before),
+		callback.Delete().After("gorm:delete").Register(metricsPluginName+":after_delete", p.after("delete")),
+		callback.Raw().Before("gorm:raw").Register(metricsPluginName+":before_raw", p.before),
+		callback.Raw().After("gorm:raw").Register(metricsPluginName+":after_raw", p.after("raw")),
+		callback.Row().Before("gorm:row").Register(metricsPluginName+":before_row", p.before),
+		callback.Row().After("gorm:row").Register(metricsPluginName+":after_row", p.after("row")),
+	}
+	for _, err := range errs {
+		if err != nil {
+			return errors.Wrap(err, "register metrics callback error")
+		}
+	}
+	return nil
+}
+
+func (*metricsPlugin) before(db *gorm.DB) {
+	db.InstanceSet(metricsStartKey, time.Now())
+}
+
+func (p *metricsPlugin) after(operation string) func(db *gorm.DB) {
+	return func(db *gorm.DB) {
+		value, ok := db.InstanceGet(metricsStartKey)
+		if !ok {
+			return
+		}
+		start, ok := value.(time.Time)
+		if !ok {
+			return
+		}
+		outcome := MetricOutcomeSuccess
+		if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) {
+			outcome = MetricOutcomeError
+		}
+		labels := []metrics.Label{
+			telemetry.NewLabel(MetricLabelNameDatabase, p.database),
+			telemetry.NewLabel(MetricLabelNameOperation, operation),
+			telemetry.NewLabel(MetricLabelNameTable, db.Statement.Table),
+			telemetry.NewLabel(MetricLabelNameOutcome, outcome),
+		}
+		telemetry.MeasureSinceWithLabels(MetricKeyStatementLatency, start, labels)
+		if outcome == MetricOutcomeError {
+			telemetry.IncrCounterWithLabels(MetricKeyStatementErrors, 1, labels)
+		}
+	}
+}
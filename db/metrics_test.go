@@ -0,0 +1,56 @@
+package db_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/armon/go-metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/pundiai/go-sdk/db"
+	"github.com/pundiai/go-sdk/log"
+)
+
+func TestStatementMetrics(t *testing.T) {
+	sink := metrics.NewInmemSink(time.Minute, time.Minute)
+	metricsConf := metrics.DefaultConfig("test")
+	metricsConf.EnableHostname = false
+	metricsConf.EnableRuntimeMetrics = false
+	_, err := metrics.NewGlobal(metricsConf, sink)
+	require.NoError(t, err)
+
+	config := db.NewDefConfig()
+	config.Source = "file:statement-metrics.db?mode=memory"
+	config.EnableMetric = false
+	config.EnableStatementMetric = true
+	testDB, err := db.NewDB(context.Background(), log.NewNopLogger(), config)
+	require.NoError(t, err)
+	defer testDB.Close()
+
+	require.NoError(t, testDB.AutoMigrate(&gorm.Model{}))
+	require.NoError(t, testDB.Create(&gorm.Model{ID: 1}))
+	require.Error(t, testDB.Create(&gorm.Model{ID: 1}))
+	_, err = testDB.First(&gorm.Model{}, 2)
+	require.NoError(t, err)
+
+	intervals := sink.Data()
+	require.NotEmpty(t, intervals)
+	var samples, counters []string
+	for _, interval := range intervals {
+		for name := range interval.Samples {
+			samples = append(samples, name)
+		}
+		for name := range interval.Counters {
+			counters = append(counters, name)
+		}
+	}
+	joined := strings.Join(samples, "\n")
+	assert.Contains(t, joined, "test.db.statement.latency;database=statement-metrics;operation=create;table=model;outcome=success")
+	assert.Contains(t, joined, "test.db.statement.latency;database=statement-metrics;operation=create;table=model;outcome=error")
+	assert.Contains(t, joined, "test.db.statement.latency;database=statement-metrics;operation=query;table=model;outcome=success")
+	assert.Equal(t, []string{"test.db.statement.errors;database=statement-metrics;operation=create;table=model;outcome=error"}, counters)
+}
@@ -3,6 +3,8 @@ package db
 import (
 	"context"
 	"database/sql"
+	"regexp"
+	"strings"
 
 	mysql2 "github.com/go-sql-driver/mysql"
 	"github.com/golang-migrate/migrate/v4/database"
@@ -11,21 +13,116 @@ import (
 	"github.com/pkg/errors"
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 
 	"github.com/pundiai/go-sdk/log"
 )
 
-const MysqlDriver = "mysql"
+const (
+	MysqlDriver = "mysql"
+
+	defaultMysqlCharset   = "utf8mb4"
+	defaultMysqlCollation = "utf8mb4_unicode_ci"
+)
+
+var mysqlCharsetPattern = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+
+// MysqlConfig holds the options of the database created by CreateDB
+type MysqlConfig struct {
+	// Charset and Collation default to utf8mb4 and utf8mb4_unicode_ci
+	Charset   string `yaml:"charset" mapstructure:"charset"`
+	Collation string `yaml:"collation" mapstructure:"collation"`
+	// GrantUser is created along with the database and granted all privileges on
+	// it when not empty, identified by the password read from GrantPasswordFile
+	GrantUser         string `yaml:"grant_user" mapstructure:"grant_user"`
+	GrantHost         string `yaml:"grant_host" mapstructure:"grant_host"`
+	GrantPasswordFile string `yaml:"grant_password_file" mapstructure:"grant_password_file"`
+}
+
+func (c MysqlConfig) validate() error {
+	if c.Charset != "" && !mysqlCharsetPattern.MatchString(c.Charset) {
+		return errors.New("charset must only contain letters, digits and underscores")
+	}
+	if c.Collation != "" && !mysqlCharsetPattern.MatchString(c.Collation) {
+		return errors.New("collation must only contain letters, digits and underscores")
+	}
+	if !strings.HasPrefix(c.collation(), c.charset()+"_") {
+		return errors.Errorf("collation %s does not belong to charset %s", c.collation(), c.charset())
+	}
+	if c.GrantUser != "" && (c.GrantHost == "" || c.GrantPasswordFile == "") {
+		return errors.New("grant_host and grant_password_file are required by grant_user")
+	}
+	return nil
+}
+
+func (c MysqlConfig) charset() string {
+	if c.Charset == "" {
+		return defaultMysqlCharset
+	}
+	return c.Charset
+}
+
+// collation falls back to the default collation of a custom charset
+func (c MysqlConfig) collation() string {
+	if c.Collation != "" {
+		return c.Collation
+	}
+	if c.Charset == "" || c.Charset == defaultMysqlCharset {
+		return defaultMysqlCollation
+	}
+	return c.Charset + "_general_ci"
+}
+
+func (c MysqlConfig) createDatabaseSQL(databaseName string) string {
+	return "CREATE DATABASE IF NOT EXISTS " + quoteMysqlIdentifier(databaseName) +
+		" DEFAULT CHARACTER SET " + c.charset() + " COLLATE " + c.collation()
+}
+
+func (c MysqlConfig) grantUserSQL(databaseName, password string) []string {
+	account := quoteMysqlString(c.GrantUser) + "@" + quoteMysqlString(c.GrantHost)
+	identified := " IDENTIFIED BY " + quoteMysqlString(password)
+	// an existing user keeps its password on CREATE USER IF NOT EXISTS, ALTER USER
+	// sets it again so that a rotated password file takes effect
+	return []string{
+		"CREATE USER IF NOT EXISTS " + account + identified,
+		"ALTER USER " + account + identified,
+		"GRANT ALL PRIVILEGES ON " + quoteMysqlIdentifier(escapeMysqlGrantPattern(databaseName)) + ".* TO " + account,
+	}
+}
+
+// escapeMysqlGrantPattern escapes the _ and % wildcards of a database name in a
+// grant, which would otherwise extend it to the databases matching the pattern
+func escapeMysqlGrantPattern(name string) string {
+	return strings.NewReplacer(`\`, `\\`, "_", `\_`, "%", `\%`).Replace(name)
+}
+
+func quoteMysqlIdentifier(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
+
+// quoteMysqlString quotes a string literal, escaping backslashes as well since
+// they are escape characters unless NO_BACKSLASH_ESCAPES is set
+func quoteMysqlString(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
 
 func init() {
 	RegisterDriver(MysqlDriver, &Mysql{})
 }
 
-var _ Driver = (*Mysql)(nil)
+var (
+	_ Driver            = (*Mysql)(nil)
+	_ CredentialsDriver = (*Mysql)(nil)
+	_ UpsertDriver      = (*Mysql)(nil)
+	_ PlaceholderDriver = (*Mysql)(nil)
+	_ LockingDriver     = (*Mysql)(nil)
+	_ ErrorClassifier   = (*Mysql)(nil)
+	_ MigrateDriver     = (*Mysql)(nil)
+	_ DryRunDriver      = (*Mysql)(nil)
+)
 
-type Mysql struct {
-	config *mysql2.Config
-}
+type Mysql struct{}
 
 func (m *Mysql) ToMigrateDriver(source string) (string, database.Driver, error) {
 	db, err := sql.Open(MysqlDriver, source)
@@ -43,48 +140,96 @@ func (*Mysql) Open(source string) gorm.Dialector {
 	return mysql.Open(source)
 }
 
-func (m *Mysql) ParseSource(source string) error {
-	if m.config != nil {
-		return nil
+func (*Mysql) OpenResolved(resolve func() (string, error)) gorm.Dialector {
+	return mysql.New(mysql.Config{Conn: sql.OpenDB(newSourceConnector(&mysql2.MySQLDriver{}, resolve))})
+}
+
+// DryRunDialector skips the server version query, the pool opened on the dummy
+// source never connects.
+func (*Mysql) DryRunDialector() gorm.Dialector {
+	return mysql.New(mysql.Config{DSN: "recorder@tcp(127.0.0.1:3306)/recorder", SkipInitializeWithVersion: true})
+}
+
+// Credentials splits the credentials the way mysql.ParseDSN does, the password
+// ends at the last '@' before the database name and may contain '@' itself.
+func (*Mysql) Credentials(source string) (user, password string, err error) {
+	at := mysqlCredentialsEnd(source)
+	if at < 0 {
+		return "", "", nil
+	}
+	user, password, _ = strings.Cut(source[:at], ":")
+	return user, password, nil
+}
+
+// WithCredentials replaces the credentials in place, leaving the rest of source untouched
+func (*Mysql) WithCredentials(source, user, password string) (string, error) {
+	credentials := user
+	if password != "" {
+		credentials += ":" + password
 	}
+	at := mysqlCredentialsEnd(source)
+	if at < 0 {
+		return credentials + "@" + source, nil
+	}
+	return credentials + source[at:], nil
+}
+
+func mysqlCredentialsEnd(source string) int {
+	slash := strings.LastIndex(source, "/")
+	if slash < 0 {
+		slash = len(source)
+	}
+	return strings.LastIndex(source[:slash], "@")
+}
+
+func (*Mysql) ParseSource(source string) error {
+	_, err := parseMysqlSource(source)
+	return err
+}
+
+func parseMysqlSource(source string) (*mysql2.Config, error) {
 	if source == "" {
-		return errors.New("mysql: source is empty")
+		return nil, errors.New("mysql: source is empty")
 	}
 	config, err := mysql2.ParseDSN(source)
 	if err != nil {
-		return errors.Wrap(err, "mysql: parse dsn error")
+		return nil, errors.Wrap(err, "mysql: parse dsn error")
 	}
 	if config.DBName == "" {
-		return errors.New("mysql: db name is empty")
+		return nil, errors.New("mysql: db name is empty")
 	}
 	if config.User == "" {
-		return errors.New("mysql: db user is empty")
+		return nil, errors.New("mysql: db user is empty")
 	}
 	if config.Passwd == "" {
-		return errors.New("mysql: db password is empty")
+		return nil, errors.New("mysql: db password is empty")
 	}
 	if config.Addr == "" {
-		return errors.New("mysql: db address is empty")
+		return nil, errors.New("mysql: db address is empty")
 	}
 	if config.Net == "" {
-		return errors.New("mysql: db net is empty")
+		return nil, errors.New("mysql: db net is empty")
 	}
-	m.config = config
-	return nil
+	return config, nil
 }
 
-func (m *Mysql) GetDatabaseName(source string) string {
-	if err := m.ParseSource(source); err != nil {
+func (*Mysql) GetDatabaseName(source string) string {
+	config, err := parseMysqlSource(source)
+	if err != nil {
 		panic(err)
 	}
-	return m.config.DBName
+	return config.DBName
 }
 
-func (m *Mysql) getInformationDB(logger log.Logger, config Config) (DB, error) {
-	if err := m.ParseSource(config.Source); err != nil {
+func (*Mysql) getInformationDB(logger log.Logger, config Config) (DB, error) {
+	source, err := config.ResolveSource()
+	if err != nil {
+		return nil, err
+	}
+	c, err := parseMysqlSource(source)
+	if err != nil {
 		return nil, err
 	}
-	c := *m.config
 	c.DBName = "information_schema"
 	config.Source = c.FormatDSN()
 	db, err := NewDB(context.Background(), logger, config)
@@ -99,12 +244,33 @@ func (m *Mysql) CreateDB(logger log.Logger, config Config) error {
 	if err != nil {
 		return err
 	}
+	defer func() { _ = db.Close() }()
+
 	databaseName := config.GetDatabaseName()
-	createDbSQL := "CREATE DATABASE IF NOT EXISTS " + databaseName + " DEFAULT CHARSET utf8 COLLATE utf8_general_ci;"
-	if err = db.Exec(createDbSQL); err != nil {
+	if err = db.Exec(config.Mysql.createDatabaseSQL(databaseName)); err != nil {
 		return err
 	}
 	logger.Info("create database success", "database", databaseName)
+	if config.Mysql.GrantUser == "" {
+		return nil
+	}
+	return m.grantUser(logger, db, config.Mysql, databaseName)
+}
+
+// grantUser runs the statements without logging them, as they carry the password
+func (*Mysql) grantUser(logger log.Logger, db DB, config MysqlConfig, databaseName string) error {
+	password, err := readPasswordFile(config.GrantPasswordFile)
+	if err != nil {
+		return errors.WithMessage(err, "mysql: grant user error")
+	}
+	db = db.WithLogger(log.NewNopLogger())
+	for _, statement := range config.grantUserSQL(databaseName, password) {
+		if err = db.Exec(statement); err != nil {
+			logger.Error("mysql: grant user error", "database", databaseName, "user", config.GrantUser, "error", err)
+			return errors.WithMessage(err, "mysql: grant user error")
+		}
+	}
+	logger.Info("grant user success", "database", databaseName, "user", config.GrantUser, "host", config.GrantHost)
 	return nil
 }
 
@@ -113,9 +279,10 @@ func (m *Mysql) DropDB(logger log.Logger, config Config) error {
 	if err != nil {
 		return err
 	}
+	defer func() { _ = db.Close() }()
+
 	databaseName := config.GetDatabaseName()
-	dropDbSQL := "DROP DATABASE IF EXISTS " + databaseName + ";"
-	if err = db.Exec(dropDbSQL); err != nil {
+	if err = db.Exec("DROP DATABASE IF EXISTS " + quoteMysqlIdentifier(databaseName)); err != nil {
 		return err
 	}
 	logger.Info("drop database success", "database", databaseName)
@@ -126,8 +293,52 @@ func (*Mysql) GetMigrationsDriver() (source.Driver, error) {
 	return GetMigrationsDriver(MysqlDriver)
 }
 
-func (*Mysql) MigrateOptions() map[string]string {
+// UpsertRowsAffected follows ON DUPLICATE KEY UPDATE, which counts 1 for an
+// inserted row, 2 for an updated row and 0 for a row left unchanged.
+func (*Mysql) UpsertRowsAffected(rows int64) (minimum, maximum int64) {
+	return 0, rows * 2
+}
+
+func (*Mysql) MaxPlaceholders() int {
+	return 65535
+}
+
+// ForUpdateClause requires mysql 8.0 or later for SKIP LOCKED and NOWAIT
+func (*Mysql) ForUpdateClause(skipLocked, noWait bool) clause.Expression {
+	return forUpdateLocking(skipLocked, noWait)
+}
+
+// IsRetryableError reports ER_LOCK_DEADLOCK (1213) and ER_LOCK_WAIT_TIMEOUT (1205)
+func (*Mysql) IsRetryableError(err error) bool {
+	var mysqlErr *mysql2.MySQLError
+	if !errors.As(err, &mysqlErr) {
+		return false
+	}
+	return mysqlErr.Number == 1213 || mysqlErr.Number == 1205
+}
+
+func (*Mysql) ClassifyError(err error) error {
+	var mysqlErr *mysql2.MySQLError
+	if !errors.As(err, &mysqlErr) {
+		return nil
+	}
+	switch mysqlErr.Number {
+	case 1062: // ER_DUP_ENTRY
+		return ErrDuplicateKey
+	case 1216, 1217, 1451, 1452: // ER_NO_REFERENCED_ROW, ER_ROW_IS_REFERENCED and their _2 variants
+		return ErrForeignKey
+	default:
+		return nil
+	}
+}
+
+func (m *Mysql) MigrateOptions() map[string]string {
+	return m.ConfigMigrateOptions(Config{})
+}
+
+// ConfigMigrateOptions creates the tables with the charset and collation of the database
+func (*Mysql) ConfigMigrateOptions(config Config) map[string]string {
 	return map[string]string{
-		"gorm:table_options": "ENGINE=InnoDB DEFAULT CHARSET=utf8mb4",
+		"gorm:table_options": "ENGINE=InnoDB DEFAULT CHARSET=" + config.Mysql.charset() + " COLLATE=" + config.Mysql.collation(),
 	}
 }
@@ -36,3 +36,51 @@ func TestMysql_CheckSource(t *testing.T) {
 		})
 	}
 }
+
+func TestMysqlConfig_CreateDatabaseSQL(t *testing.T) {
+	config := NewDefConfig().Mysql
+	assert.NoError(t, config.validate())
+	assert.Equal(t, "CREATE DATABASE IF NOT EXISTS `my``db` DEFAULT CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci",
+		config.createDatabaseSQL("my`db"))
+
+	config = MysqlConfig{Charset: "latin1"}
+	assert.NoError(t, config.validate())
+	assert.Equal(t, "CREATE DATABASE IF NOT EXISTS `my` DEFAULT CHARACTER SET latin1 COLLATE latin1_general_ci",
+		config.createDatabaseSQL("my"))
+
+	config = MysqlConfig{Charset: "utf8mb4; DROP DATABASE my"}
+	assert.EqualError(t, config.validate(), "charset must only contain letters, digits and underscores")
+	config = MysqlConfig{Collation: "latin1_swedish_ci"}
+	assert.EqualError(t, config.validate(), "collation latin1_swedish_ci does not belong to charset utf8mb4")
+	config = MysqlConfig{GrantUser: "app"}
+	assert.EqualError(t, config.validate(), "grant_host and grant_password_file are required by grant_user")
+}
+
+func TestMysqlConfig_GrantUserSQL(t *testing.T) {
+	config := MysqlConfig{GrantUser: "app'", GrantHost: "%", GrantPasswordFile: "password"}
+	assert.NoError(t, config.validate())
+	assert.Equal(t, []string{
+		`CREATE USER IF NOT EXISTS 'app'''@'%' IDENTIFIED BY 'p\\a''ss'`,
+		`ALTER USER 'app'''@'%' IDENTIFIED BY 'p\\a''ss'`,
+		"GRANT ALL PRIVILEGES ON `my`.* TO 'app'''@'%'",
+	}, config.grantUserSQL("my", `p\a'ss`))
+}
+
+func TestMysqlConfig_GrantUserSQLPattern(t *testing.T) {
+	config := MysqlConfig{GrantUser: "app", GrantHost: "%", GrantPasswordFile: "password"}
+	statements := config.grantUserSQL(`my_db%\`, "pass")
+	assert.Equal(t, "GRANT ALL PRIVILEGES ON `my\\_db\\%\\\\`.* TO 'app'@'%'", statements[2])
+}
+
+func TestMysql_MigrateOptions(t *testing.T) {
+	m := &Mysql{}
+	assert.Equal(t, map[string]string{
+		"gorm:table_options": "ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci",
+	}, m.MigrateOptions())
+
+	config := NewDefConfig()
+	config.Mysql = MysqlConfig{Charset: "latin1"}
+	assert.Equal(t, map[string]string{
+		"gorm:table_options": "ENGINE=InnoDB DEFAULT CHARSET=latin1 COLLATE=latin1_general_ci",
+	}, migrateOptions(m, config))
+}
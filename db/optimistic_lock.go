@@ -0,0 +1,119 @@
+package db
+
+import (
+	"reflect"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/callbacks"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	optimisticLockPluginName = "sdk:optimistic_lock"
+	optimisticLockVersionKey = "sdk:optimistic_lock:version"
+
+	versionColumn = "version"
+)
+
+var _ gorm.Plugin = (*optimisticLockPlugin)(nil)
+
+// versioned is implemented by the models embedding model.Versioned
+type versioned interface {
+	GetVersion() uint64
+}
+
+// optimisticLockPlugin increments the version of the updated rows, and only
+// updates the row at the version carried by the values or the model. A zero
+// version skips the check, as the version the caller read is unknown.
+type optimisticLockPlugin struct{}
+
+func (*optimisticLockPlugin) Name() string {
+	return optimisticLockPluginName
+}
+
+func (p *optimisticLockPlugin) Initialize(db *gorm.DB) error {
+	callback := db.Callback()
+	if err := callback.Update().Before("gorm:update").Register(optimisticLockPluginName+":before_update", p.before); err != nil {
+		return errors.Wrap(err, "register optimistic lock callback error")
+	}
+	if err := callback.Update().After("gorm:update").Register(optimisticLockPluginName+":after_update", p.after); err != nil {
+		return errors.Wrap(err, "register optimistic lock callback error")
+	}
+	return nil
+}
+
+// lockVersion is the version an update expects, read from the updated values or
+// else from the model
+type lockVersion struct {
+	version   uint64
+	fromModel bool
+}
+
+func (*optimisticLockPlugin) before(db *gorm.DB) {
+	stmt := db.Statement
+	if db.Error != nil || stmt.Schema == nil || stmt.SQL.Len() > 0 {
+		return
+	}
+	model, ok := stmt.Model.(versioned)
+	if !ok {
+		return
+	}
+	if _, ok = stmt.Clauses["SET"]; ok {
+		return
+	}
+	// read the version before converting the values, which copies them to the model
+	lock := lockVersion{version: model.GetVersion(), fromModel: true}
+	if values, ok := stmt.Dest.(versioned); ok {
+		lock = lockVersion{version: values.GetVersion()}
+	}
+
+	set := callbacks.ConvertToAssignments(stmt)
+	if len(set) == 0 {
+		return
+	}
+	assignments := make(clause.Set, 0, len(set)+1)
+	for _, assignment := range set {
+		if assignment.Column.Name != versionColumn {
+			assignments = append(assignments, assignment)
+		}
+	}
+	assignments = append(assignments, clause.Assignment{
+		Column: clause.Column{Name: versionColumn},
+		Value:  clause.Expr{SQL: "? + 1", Vars: []any{clause.Column{Name: versionColumn}}},
+	})
+	stmt.AddClause(assignments)
+
+	db.InstanceSet(optimisticLockVersionKey, lock)
+	if lock.version > 0 {
+		stmt.AddClause(clause.Where{Exprs: []clause.Expression{
+			clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: versionColumn}, Value: lock.version},
+		}})
+	}
+}
+
+func (*optimisticLockPlugin) after(db *gorm.DB) {
+	value, ok := db.InstanceGet(optimisticLockVersionKey)
+	if !ok {
+		return
+	}
+	// the SET clause belongs to this update only, unlike the conditions
+	delete(db.Statement.Clauses, "SET")
+	lock, ok := value.(lockVersion)
+	if !ok || lock.version == 0 || db.Error != nil {
+		return
+	}
+	if db.RowsAffected == 0 {
+		_ = db.AddError(ErrStaleVersion)
+		return
+	}
+	// keep the struct the version was read from in sync with the row
+	target := db.Statement.ReflectValue
+	if !lock.fromModel {
+		target = reflect.Indirect(reflect.ValueOf(db.Statement.Dest))
+	}
+	field := db.Statement.Schema.LookUpField(versionColumn)
+	if field != nil && target.Kind() == reflect.Struct && target.CanAddr() && target.Type() == db.Statement.Schema.ModelType {
+		_ = db.AddError(field.Set(db.Statement.Context, target, lock.version+1))
+	}
+}
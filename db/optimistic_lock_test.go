@@ -0,0 +1,53 @@
+package db_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pundiai/go-sdk/db"
+	"github.com/pundiai/go-sdk/log"
+	"github.com/pundiai/go-sdk/model"
+)
+
+type versionedModel struct {
+	model.Base      `gorm:"embedded"`
+	model.Versioned `gorm:"embedded"`
+
+	Name string
+}
+
+func TestOptimisticLock(t *testing.T) {
+	testDB := db.NewMemoryDB(log.LevelError, "optimistic-lock")
+	require.NoError(t, testDB.AutoMigrate(&versionedModel{}))
+	require.NoError(t, testDB.Create(&versionedModel{Name: "test"}))
+
+	var first, second versionedModel
+	_, err := testDB.Where("id = ?", 1).First(&first)
+	require.NoError(t, err)
+	_, err = testDB.Where("id = ?", 1).First(&second)
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, first.Version)
+
+	first.Name = "first"
+	require.NoError(t, testDB.Updates(&first))
+	assert.EqualValues(t, 2, first.Version)
+
+	second.Name = "second"
+	err = testDB.Updates(&second)
+	require.ErrorIs(t, err, db.ErrStaleVersion)
+	assert.EqualValues(t, 1, second.Version)
+
+	// updates without a known version still increment it
+	require.NoError(t, testDB.Model(&versionedModel{}).Where("id = ?", 1).Update("name", "third"))
+	var actual versionedModel
+	_, err = testDB.Where("id = ?", 1).First(&actual)
+	require.NoError(t, err)
+	assert.Equal(t, "third", actual.Name)
+	assert.EqualValues(t, 3, actual.Version)
+
+	err = testDB.Model(&versionedModel{}).Where("id = ?", 1).Updates(&versionedModel{Name: "fourth", Versioned: first.Versioned})
+	require.ErrorIs(t, err, db.ErrStaleVersion)
+	require.NoError(t, testDB.Model(&versionedModel{}).Where("id = ?", 1).Updates(&versionedModel{Name: "fourth", Versioned: actual.Versioned}))
+}
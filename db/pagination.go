@@ -0,0 +1,164 @@
+package db
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	PageOrderByID        = "id"
+	PageOrderByCreatedAt = "created_at"
+
+	DefaultPageLimit = 20
+	MaxPageLimit     = 1000
+)
+
+// PageRequest asks for the page after or before a cursor returned by a previous
+// Paginate, or for the first page when both are empty.
+type PageRequest struct {
+	After  string
+	Before string
+	// Limit defaults to DefaultPageLimit
+	Limit int
+	// OrderBy is "id" or "created_at", optionally followed by "desc", it defaults
+	// to "id". Rows created at the same time are ordered by id.
+	OrderBy string
+}
+
+// Page holds the cursors of the pages next to the returned one, a cursor is
+// empty when there is no such page.
+type Page struct {
+	Next string
+	Prev string
+}
+
+type pageCursor struct {
+	OrderBy   string     `json:"o"`
+	ID        uint       `json:"i"`
+	CreatedAt *time.Time `json:"c,omitempty"`
+}
+
+func (p PageRequest) order() (column string, desc bool, err error) {
+	orderBy := strings.Fields(strings.ToLower(p.OrderBy))
+	switch {
+	case len(orderBy) == 0:
+		return PageOrderByID, false, nil
+	case len(orderBy) > 2 || (len(orderBy) == 2 && orderBy[1] != "asc" && orderBy[1] != "desc"):
+		return "", false, errors.Errorf("invalid order by: %s", p.OrderBy)
+	case orderBy[0] != PageOrderByID && orderBy[0] != PageOrderByCreatedAt:
+		return "", false, errors.Errorf("unsupported order by column: %s", orderBy[0])
+	}
+	return orderBy[0], len(orderBy) == 2 && orderBy[1] == "desc", nil
+}
+
+func (p PageRequest) limit() (int, error) {
+	switch {
+	case p.Limit == 0:
+		return DefaultPageLimit, nil
+	case p.Limit < 0 || p.Limit > MaxPageLimit:
+		return 0, errors.Errorf("limit is invalid, must between 1 and %d", MaxPageLimit)
+	default:
+		return p.Limit, nil
+	}
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// checkPageElem makes sure the cursor can be read from the elements of dest, a
+// struct or a pointer to struct with an unsigned ID and, when ordered by it, a
+// time.Time CreatedAt
+func checkPageElem(elem reflect.Type, column string) error {
+	if elem.Kind() == reflect.Pointer {
+		elem = elem.Elem()
+	}
+	if elem.Kind() != reflect.Struct {
+		return errors.Errorf("dest element must be a struct, got %s", elem)
+	}
+	id, ok := elem.FieldByName("ID")
+	if !ok {
+		return errors.Errorf("dest element %s has no ID field", elem)
+	}
+	switch id.Type.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+	default:
+		return errors.Errorf("dest element %s ID must be unsigned, got %s", elem, id.Type)
+	}
+	if column == PageOrderByCreatedAt {
+		createdAt, ok := elem.FieldByName("CreatedAt")
+		if !ok || createdAt.Type != timeType {
+			return errors.Errorf("dest element %s has no time.Time CreatedAt field", elem)
+		}
+	}
+	return nil
+}
+
+func encodePageCursor(orderBy string, item reflect.Value) string {
+	item = reflect.Indirect(item)
+	cursor := pageCursor{OrderBy: orderBy, ID: uint(item.FieldByName("ID").Uint())}
+	if orderBy == PageOrderByCreatedAt {
+		createdAt := item.FieldByName("CreatedAt").Interface().(time.Time)
+		cursor.CreatedAt = &createdAt
+	}
+	data, err := json.Marshal(cursor)
+	if err != nil {
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodePageCursor(orderBy, token string) (*pageCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, errors.Wrap(ErrInvalidCursor, err.Error())
+	}
+	cursor := new(pageCursor)
+	if err = json.Unmarshal(data, cursor); err != nil {
+		return nil, errors.Wrap(ErrInvalidCursor, err.Error())
+	}
+	if cursor.OrderBy != orderBy || (orderBy == PageOrderByCreatedAt && cursor.CreatedAt == nil) {
+		return nil, errors.Wrapf(ErrInvalidCursor, "cursor is not ordered by %s", orderBy)
+	}
+	return cursor, nil
+}
+
+// keysetClauses returns the condition selecting the rows after the cursor in the
+// given direction and the matching order, backward reverses the order.
+func keysetClauses(column string, desc, backward bool, cursor *pageCursor) (clause.Expression, clause.OrderBy) {
+	desc = desc != backward
+	idColumn := clause.Column{Table: clause.CurrentTable, Name: PageOrderByID}
+	orderBy := clause.OrderBy{Columns: []clause.OrderByColumn{{Column: idColumn, Desc: desc}}}
+	if column != PageOrderByID {
+		orderColumn := clause.Column{Table: clause.CurrentTable, Name: column}
+		orderBy.Columns = append([]clause.OrderByColumn{{Column: orderColumn, Desc: desc}}, orderBy.Columns...)
+	}
+	if cursor == nil {
+		return nil, orderBy
+	}
+	beyond := func(column clause.Column, value any) clause.Expression {
+		if desc {
+			return clause.Lt{Column: column, Value: value}
+		}
+		return clause.Gt{Column: column, Value: value}
+	}
+	if column == PageOrderByID {
+		return beyond(idColumn, cursor.ID), orderBy
+	}
+	orderColumn := clause.Column{Table: clause.CurrentTable, Name: column}
+	return clause.Or(
+		beyond(orderColumn, *cursor.CreatedAt),
+		clause.And(clause.Eq{Column: orderColumn, Value: *cursor.CreatedAt}, beyond(idColumn, cursor.ID)),
+	), orderBy
+}
+
+func reverseSlice(slice reflect.Value) {
+	swap := reflect.Swapper(slice.Interface())
+	for i, j := 0, slice.Len()-1; i < j; i, j = i+1, j-1 {
+		swap(i, j)
+	}
+}
@@ -0,0 +1,102 @@
+package db_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pundiai/go-sdk/db"
+	"github.com/pundiai/go-sdk/log"
+	"github.com/pundiai/go-sdk/model"
+)
+
+type pageModel struct {
+	model.Base `gorm:"embedded"`
+}
+
+func pageIDs(models []pageModel) []uint {
+	ids := make([]uint, 0, len(models))
+	for _, m := range models {
+		ids = append(ids, m.ID)
+	}
+	return ids
+}
+
+func TestPaginate(t *testing.T) {
+	testDB := db.NewMemoryDB(log.LevelError, "paginate")
+	require.NoError(t, testDB.AutoMigrate(&pageModel{}))
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	// ids 1..7 are created at 6, 5, 4, 4, 3, 2, 1 seconds after start
+	for i, second := range []int{6, 5, 4, 4, 3, 2, 1} {
+		createdAt := start.Add(time.Duration(second) * time.Second)
+		require.NoError(t, testDB.Create(&pageModel{Base: model.Base{ID: uint(i + 1), CreatedAt: createdAt}}))
+	}
+
+	var models []pageModel
+	page, err := testDB.Paginate(&models, db.PageRequest{Limit: 3})
+	require.NoError(t, err)
+	assert.Equal(t, []uint{1, 2, 3}, pageIDs(models))
+	assert.Empty(t, page.Prev)
+
+	page, err = testDB.Paginate(&models, db.PageRequest{After: page.Next, Limit: 3})
+	require.NoError(t, err)
+	assert.Equal(t, []uint{4, 5, 6}, pageIDs(models))
+
+	page, err = testDB.Paginate(&models, db.PageRequest{After: page.Next, Limit: 3})
+	require.NoError(t, err)
+	assert.Equal(t, []uint{7}, pageIDs(models))
+	assert.Empty(t, page.Next)
+
+	page, err = testDB.Paginate(&models, db.PageRequest{Before: page.Prev, Limit: 3})
+	require.NoError(t, err)
+	assert.Equal(t, []uint{4, 5, 6}, pageIDs(models))
+	page, err = testDB.Paginate(&models, db.PageRequest{Before: page.Prev, Limit: 3})
+	require.NoError(t, err)
+	assert.Equal(t, []uint{1, 2, 3}, pageIDs(models))
+	assert.Empty(t, page.Prev)
+	assert.NotEmpty(t, page.Next)
+
+	// rows created at the same time are ordered by id
+	page, err = testDB.Paginate(&models, db.PageRequest{Limit: 3, OrderBy: "created_at"})
+	require.NoError(t, err)
+	assert.Equal(t, []uint{7, 6, 5}, pageIDs(models))
+	page, err = testDB.Paginate(&models, db.PageRequest{After: page.Next, Limit: 3, OrderBy: "created_at"})
+	require.NoError(t, err)
+	assert.Equal(t, []uint{3, 4, 2}, pageIDs(models))
+	page, err = testDB.Paginate(&models, db.PageRequest{Before: page.Prev, Limit: 3, OrderBy: "created_at"})
+	require.NoError(t, err)
+	assert.Equal(t, []uint{7, 6, 5}, pageIDs(models))
+
+	page, err = testDB.Paginate(&models, db.PageRequest{Limit: 4, OrderBy: "created_at desc"})
+	require.NoError(t, err)
+	assert.Equal(t, []uint{1, 2, 4, 3}, pageIDs(models))
+	_, err = testDB.Paginate(&models, db.PageRequest{After: page.Next, Limit: 4, OrderBy: "created_at desc"})
+	require.NoError(t, err)
+	assert.Equal(t, []uint{5, 6, 7}, pageIDs(models))
+
+	// a cursor only continues the order it was created with
+	_, err = testDB.Paginate(&models, db.PageRequest{After: page.Next})
+	require.ErrorIs(t, err, db.ErrInvalidCursor)
+	_, err = testDB.Paginate(&models, db.PageRequest{After: "invalid"})
+	require.ErrorIs(t, err, db.ErrInvalidCursor)
+	_, err = testDB.Paginate(&models, db.PageRequest{OrderBy: "name"})
+	require.EqualError(t, err, "db paginate error: unsupported order by column: name")
+	_, err = testDB.Scopes(db.OrderByDesc("id")).Paginate(&models, db.PageRequest{})
+	require.EqualError(t, err, "db paginate error: query is already ordered, order by the page request instead")
+
+	// dest elements the cursor can't be read from are rejected before the query
+	type signedModel struct {
+		ID int64
+	}
+	_, err = testDB.Model(&pageModel{}).Paginate(&[]signedModel{}, db.PageRequest{})
+	require.EqualError(t, err, "db paginate error: dest element db_test.signedModel ID must be unsigned, got int64")
+	type unnamedModel struct {
+		ID uint
+	}
+	_, err = testDB.Model(&pageModel{}).Paginate(&[]*unnamedModel{}, db.PageRequest{OrderBy: "created_at"})
+	require.EqualError(t, err, "db paginate error: dest element db_test.unnamedModel has no time.Time CreatedAt field")
+	_, err = testDB.Model(&pageModel{}).Paginate(&[]map[string]any{}, db.PageRequest{})
+	require.EqualError(t, err, "db paginate error: dest element must be a struct, got map[string]interface {}")
+}
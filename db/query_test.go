@@ -0,0 +1,86 @@
+package db_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm/clause"
+
+	"github.com/pundiai/go-sdk/db"
+	"github.com/pundiai/go-sdk/log"
+)
+
+type queryAuthor struct {
+	ID    uint
+	Name  string `gorm:"uniqueIndex"`
+	Email string
+	Books []queryBook `gorm:"foreignKey:AuthorID"`
+}
+
+type queryBook struct {
+	ID       uint
+	AuthorID uint
+	Author   *queryAuthor
+	Title    string
+	Pages    int
+}
+
+func TestQueryBuilders(t *testing.T) {
+	testDB := db.NewMemoryDB(log.LevelError, "query-builders")
+	require.NoError(t, testDB.AutoMigrate(&queryAuthor{}, &queryBook{}))
+	require.NoError(t, testDB.Create(&queryAuthor{ID: 1, Name: "alice", Books: []queryBook{
+		{ID: 1, Title: "a1", Pages: 100},
+		{ID: 2, Title: "a2", Pages: 300},
+	}}))
+	require.NoError(t, testDB.Create(&queryAuthor{ID: 2, Name: "bob", Books: []queryBook{
+		{ID: 3, Title: "b1", Pages: 50},
+	}}))
+
+	var authors []queryAuthor
+	require.NoError(t, testDB.Preload("Books", "pages > ?", 60).Order("id").Find(&authors))
+	require.Len(t, authors, 2)
+	assert.Len(t, authors[0].Books, 2)
+	assert.Empty(t, authors[1].Books)
+
+	var books []queryBook
+	require.NoError(t, testDB.Joins("Author").Where("Author.name = ?", "bob").Find(&books))
+	require.Len(t, books, 1)
+	assert.Equal(t, "bob", books[0].Author.Name)
+
+	var titles []string
+	require.NoError(t, testDB.Model(&queryBook{}).Order("id").Pluck("title", &titles))
+	assert.Equal(t, []string{"a1", "a2", "b1"}, titles)
+
+	type authorPages struct {
+		AuthorID uint
+		Total    int
+	}
+	var totals []authorPages
+	require.NoError(t, testDB.Model(&queryBook{}).Select("author_id, SUM(pages) AS total").
+		Group("author_id").Having("SUM(pages) > ?", 100).Scan(&totals))
+	assert.Equal(t, []authorPages{{AuthorID: 1, Total: 400}}, totals)
+
+	totals = nil
+	require.NoError(t, testDB.Raw("SELECT author_id, COUNT(*) AS total FROM query_book GROUP BY author_id ORDER BY author_id").Scan(&totals))
+	assert.Equal(t, []authorPages{{AuthorID: 1, Total: 2}, {AuthorID: 2, Total: 1}}, totals)
+	assert.ErrorContains(t, testDB.Raw("SELECT * FROM missing").Scan(&totals), "db scan error")
+
+	require.NoError(t, testDB.Omit("Books").Create(&queryAuthor{ID: 3, Name: "carol", Books: []queryBook{{ID: 4}}}))
+	found, err := testDB.First(&queryBook{}, 4)
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	author := &queryAuthor{}
+	require.NoError(t, testDB.Where("name = ?", "dave").Assign(queryAuthor{Email: "dave@example.com"}).FirstOrCreate(author))
+	assert.NotZero(t, author.ID)
+	assert.Equal(t, "dave@example.com", author.Email)
+
+	require.NoError(t, testDB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "name"}},
+		DoUpdates: clause.AssignmentColumns([]string{"email"}),
+	}).Create(&queryAuthor{ID: 5, Name: "alice", Email: "alice@example.com"}))
+	author = &queryAuthor{}
+	require.NoError(t, testDB.MustFirst(author, 1))
+	assert.Equal(t, "alice@example.com", author.Email)
+}
@@ -0,0 +1,266 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+	"sync"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+
+	"github.com/pundiai/go-sdk/log"
+)
+
+const recorderPluginName = "sdk:recorder"
+
+var _ gorm.Plugin = (*Recorder)(nil)
+
+// RecordedStatement is a statement generated by a recorder DB, with its bound variables
+type RecordedStatement struct {
+	Operation string
+	Table     string
+	SQL       string
+	Vars      []any
+}
+
+// Recorder keeps the statements of a recorder DB instead of running them, and
+// serves the canned results of its queries.
+type Recorder struct {
+	mu           sync.Mutex
+	dialector    gorm.Dialector
+	statements   []RecordedStatement
+	results      map[string]any
+	rowsAffected int64
+}
+
+// NewRecorderDB returns a dry run DB of the named driver, the statements it
+// generates are recorded by the returned Recorder and never executed, so they
+// are quoted and bound the way that database expects without a server. Writes
+// report one row affected and queries find nothing unless a result is set with
+// Recorder.SetResult.
+func NewRecorderDB(driverName string) (DB, *Recorder) {
+	driver, err := GetDriver(driverName)
+	if err != nil {
+		panic(err)
+	}
+	dryRun, ok := driver.(DryRunDriver)
+	if !ok {
+		panic("recorder: driver does not support dry run: " + driverName)
+	}
+	config := NewDefConfig()
+	config.Driver = driverName
+	config.Source = ""
+	config.EnableMetric = false
+	gormConfig := newGormConfig(log.NewNopLogger(), config)
+	gormConfig.DryRun = true
+	gormConfig.DisableAutomaticPing = true
+	db, err := gorm.Open(dryRun.DryRunDialector(), gormConfig)
+	if err != nil {
+		panic(err)
+	}
+	// the dialector pool is only needed by its initialization, the default
+	// transactions and Transaction begin on a pool that never connects instead
+	if sqlDB, dbErr := db.DB(); dbErr == nil {
+		_ = sqlDB.Close()
+	}
+	db.ConnPool = dryRunPool{}
+	db.Statement.ConnPool = db.ConnPool
+	recorder := &Recorder{
+		dialector:    db.Dialector,
+		results:      make(map[string]any),
+		rowsAffected: 1,
+	}
+	if err = db.Use(&optimisticLockPlugin{}); err != nil {
+		panic(err)
+	}
+	if err = db.Use(recorder); err != nil {
+		panic(err)
+	}
+	return newGDB(log.NewNopLogger(), &config, db, driver, 0), recorder
+}
+
+// Statements returns the recorded statements in order
+func (r *Recorder) Statements() []RecordedStatement {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]RecordedStatement(nil), r.statements...)
+}
+
+// Last returns the last recorded statement, it panics when there is none
+func (r *Recorder) Last() RecordedStatement {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.statements) == 0 {
+		panic("recorder: no statement recorded")
+	}
+	return r.statements[len(r.statements)-1]
+}
+
+// Explain returns the statement with its variables inlined, for readable assertions
+func (r *Recorder) Explain(statement RecordedStatement) string {
+	return r.dialector.Explain(statement.SQL, statement.Vars...)
+}
+
+// SetResult sets the rows found by the queries of table, result is a model, a
+// slice of models or a value such as the int64 of Count.
+func (r *Recorder) SetResult(table string, result any) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.results[table] = result
+}
+
+// SetRowsAffected sets the rows affected reported by the writes, one by default
+func (r *Recorder) SetRowsAffected(rowsAffected int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.rowsAffected = rowsAffected
+}
+
+// Reset forgets the recorded statements and the canned results
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.statements = nil
+	r.results = make(map[string]any)
+	r.rowsAffected = 1
+}
+
+func (*Recorder) Name() string {
+	return recorderPluginName
+}
+
+func (r *Recorder) Initialize(db *gorm.DB) error {
+	callback := db.Callback()
+	errs := []error{
+		callback.Create().After("gorm:create").Register(recorderPluginName+":create", r.write("create")),
+		callback.Query().After("gorm:query").Register(recorderPluginName+":query", r.query),
+		// report the rows affected before the optimistic lock checks them
+		callback.Update().After("gorm:update").Before(optimisticLockPluginName+":after_update").
+			Register(recorderPluginName+":update", r.write("update")),
+		callback.Delete().After("gorm:delete").Register(recorderPluginName+":delete", r.write("delete")),
+		callback.Raw().After("gorm:raw").Register(recorderPluginName+":raw", r.write("raw")),
+		callback.Row().After("gorm:row").Register(recorderPluginName+":row", r.write("row")),
+	}
+	for _, err := range errs {
+		if err != nil {
+			return errors.Wrap(err, "register recorder callback error")
+		}
+	}
+	return nil
+}
+
+var (
+	_ gorm.ConnPool         = dryRunPool{}
+	_ gorm.ConnPoolBeginner = dryRunPool{}
+	_ gorm.TxCommitter      = (*dryRunTx)(nil)
+)
+
+var errDryRun = errors.New("recorder: dry run db does not connect")
+
+// dryRunPool serves the transactions of a recorder DB, DryRun keeps gorm from
+// running any statement on it
+type dryRunPool struct{}
+
+func (dryRunPool) PrepareContext(context.Context, string) (*sql.Stmt, error) {
+	return nil, errDryRun
+}
+
+func (dryRunPool) ExecContext(context.Context, string, ...any) (sql.Result, error) {
+	return nil, errDryRun
+}
+
+func (dryRunPool) QueryContext(context.Context, string, ...any) (*sql.Rows, error) {
+	return nil, errDryRun
+}
+
+func (dryRunPool) QueryRowContext(context.Context, string, ...any) *sql.Row {
+	return nil
+}
+
+func (dryRunPool) BeginTx(context.Context, *sql.TxOptions) (gorm.ConnPool, error) {
+	return &dryRunTx{}, nil
+}
+
+type dryRunTx struct {
+	dryRunPool
+}
+
+func (*dryRunTx) Commit() error {
+	return nil
+}
+
+func (*dryRunTx) Rollback() error {
+	return nil
+}
+
+func (r *Recorder) record(db *gorm.DB, operation string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.statements = append(r.statements, RecordedStatement{
+		Operation: operation,
+		Table:     db.Statement.Table,
+		SQL:       db.Statement.SQL.String(),
+		Vars:      append([]any(nil), db.Statement.Vars...),
+	})
+}
+
+func (r *Recorder) write(operation string) func(db *gorm.DB) {
+	return func(db *gorm.DB) {
+		if db.Error != nil || !db.DryRun {
+			return
+		}
+		r.record(db, operation)
+		r.mu.Lock()
+		db.RowsAffected = r.rowsAffected
+		r.mu.Unlock()
+	}
+}
+
+func (r *Recorder) query(db *gorm.DB) {
+	if db.Error != nil || !db.DryRun {
+		return
+	}
+	r.record(db, "query")
+	r.mu.Lock()
+	result, ok := r.results[db.Statement.Table]
+	r.mu.Unlock()
+	if ok {
+		rows, err := assignResult(db.Statement.Dest, result)
+		if err != nil {
+			_ = db.AddError(err)
+			return
+		}
+		db.RowsAffected = rows
+	}
+	if db.RowsAffected == 0 && db.Statement.RaiseErrorOnNotFound {
+		_ = db.AddError(gorm.ErrRecordNotFound)
+	}
+}
+
+// assignResult copies result into dest, a slice result fills a single model
+// dest with its first element.
+func assignResult(dest, result any) (int64, error) {
+	destValue := reflect.ValueOf(dest)
+	if destValue.Kind() != reflect.Pointer || destValue.IsNil() {
+		return 0, errors.Errorf("recorder: dest %T is not a pointer", dest)
+	}
+	target := destValue.Elem()
+	value := reflect.Indirect(reflect.ValueOf(result))
+	switch {
+	case value.Type().AssignableTo(target.Type()):
+		target.Set(value)
+		if value.Kind() == reflect.Slice {
+			return int64(value.Len()), nil
+		}
+		return 1, nil
+	case value.Kind() == reflect.Slice && value.Type().Elem().AssignableTo(target.Type()):
+		if value.Len() == 0 {
+			return 0, nil
+		}
+		target.Set(value.Index(0))
+		return 1, nil
+	default:
+		return 0, errors.Errorf("recorder: result %T is not assignable to %T", result, dest)
+	}
+}
@@ -0,0 +1,74 @@
+package db_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/pundiai/go-sdk/db"
+)
+
+func TestRecorderDB(t *testing.T) {
+	recorderDB, recorder := db.NewRecorderDB(db.SqliteDriver)
+
+	require.NoError(t, recorderDB.Model(&gorm.Model{}).Where("id = ?", 1).RowsAffected(1).Update("deleted_at", nil))
+	statement := recorder.Last()
+	assert.Equal(t, "update", statement.Operation)
+	assert.Equal(t, "model", statement.Table)
+	assert.Equal(t, "UPDATE `model` SET `deleted_at`=?,`updated_at`=? WHERE id = ? AND `model`.`deleted_at` IS NULL", statement.SQL)
+	assert.Len(t, statement.Vars, 3)
+	assert.Nil(t, statement.Vars[0])
+	assert.Equal(t, 1, statement.Vars[2])
+
+	var models []gorm.Model
+	require.NoError(t, recorderDB.Where("id > ?", 1).Limit(2).Find(&models))
+	assert.Empty(t, models)
+	assert.Equal(t, "SELECT * FROM `model` WHERE id > 1 AND `model`.`deleted_at` IS NULL LIMIT 2", recorder.Explain(recorder.Last()))
+	found, err := recorderDB.First(&gorm.Model{})
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	recorder.SetResult("model", []gorm.Model{{ID: 2}, {ID: 3}})
+	require.NoError(t, recorderDB.Find(&models))
+	assert.Equal(t, []gorm.Model{{ID: 2}, {ID: 3}}, models)
+	first := &gorm.Model{}
+	found, err = recorderDB.First(first)
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.EqualValues(t, 2, first.ID)
+	recorder.SetResult("model", int64(7))
+	var count int64
+	recorderDB.Model(&gorm.Model{}).Count(&count)
+	assert.EqualValues(t, 7, count)
+
+	recorder.SetRowsAffected(0)
+	require.ErrorIs(t, recorderDB.RowsAffected(1).Delete(&gorm.Model{ID: 1}), db.ErrRowsAffectedMismatch)
+	assert.Len(t, recorder.Statements(), 7)
+
+	recorder.Reset()
+	assert.Empty(t, recorder.Statements())
+}
+
+func TestRecorderDB_Dialects(t *testing.T) {
+	tests := []struct {
+		driver string
+		sql    string
+	}{
+		{driver: db.MysqlDriver, sql: "UPDATE `model` SET `deleted_at`=?,`updated_at`=? WHERE id = ? AND `model`.`deleted_at` IS NULL"},
+		{driver: db.PostgresDriver, sql: `UPDATE "model" SET "deleted_at"=$1,"updated_at"=$2 WHERE id = $3 AND "model"."deleted_at" IS NULL`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.driver, func(t *testing.T) {
+			recorderDB, recorder := db.NewRecorderDB(tt.driver)
+			require.NoError(t, recorderDB.Model(&gorm.Model{}).Where("id = ?", 1).Update("deleted_at", nil))
+			assert.Equal(t, tt.sql, recorder.Last().SQL)
+			require.NoError(t, recorderDB.Transaction(func(tx db.DB) error {
+				return tx.Create(&gorm.Model{})
+			}))
+			assert.Equal(t, "create", recorder.Last().Operation)
+		})
+	}
+	assert.Panics(t, func() { db.NewRecorderDB("unknown") })
+}
@@ -0,0 +1,40 @@
+package db
+
+import (
+	"database/sql"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// Rows is a cursor over a query result that scans one row at a time,
+// it keeps a connection busy until it is closed.
+type Rows interface {
+	Next() bool
+	Scan(dest any) error
+	Err() error
+	Close() error
+}
+
+var _ Rows = (*gRows)(nil)
+
+type gRows struct {
+	rows *sql.Rows
+	db   *gorm.DB
+}
+
+func (r *gRows) Next() bool {
+	return r.rows.Next()
+}
+
+func (r *gRows) Scan(dest any) error {
+	return errors.Wrap(r.db.ScanRows(r.rows, dest), "db scan rows error")
+}
+
+func (r *gRows) Err() error {
+	return r.rows.Err()
+}
+
+func (r *gRows) Close() error {
+	return r.rows.Close()
+}
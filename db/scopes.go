@@ -0,0 +1,58 @@
+package db
+
+import (
+	"time"
+
+	"gorm.io/gorm/clause"
+)
+
+// ByIDs filters the rows by primary key, no ids matches no rows
+func ByIDs(ids ...uint) func(DB) DB {
+	return WhereIn("id", ids)
+}
+
+// WhereIn filters the rows whose column is one of values, no values matches no rows
+func WhereIn[T any](column string, values []T) func(DB) DB {
+	return func(db DB) DB {
+		in := make([]any, 0, len(values))
+		for _, value := range values {
+			in = append(in, value)
+		}
+		return db.Where(clause.IN{Column: clause.Column{Name: column}, Values: in})
+	}
+}
+
+// CreatedBetween filters the rows created in [start, end), a zero time leaves
+// that side unbounded
+func CreatedBetween(start, end time.Time) func(DB) DB {
+	return func(db DB) DB {
+		if !start.IsZero() {
+			db = db.Where(clause.Gte{Column: clause.Column{Name: "created_at"}, Value: start})
+		}
+		if !end.IsZero() {
+			db = db.Where(clause.Lt{Column: clause.Column{Name: "created_at"}, Value: end})
+		}
+		return db
+	}
+}
+
+// OrderByDesc orders the rows by column descending
+func OrderByDesc(column string) func(DB) DB {
+	return func(db DB) DB {
+		return db.Order(clause.OrderByColumn{Column: clause.Column{Name: column}, Desc: true})
+	}
+}
+
+// Paginate limits the rows to the page numbered from 1 by offset, size defaults
+// to DefaultPageLimit and is capped at MaxPageLimit. Prefer DB.Paginate on large
+// tables, where the offset gets slow.
+func Paginate(page, size int) func(DB) DB {
+	page = max(page, 1)
+	if size <= 0 {
+		size = DefaultPageLimit
+	}
+	size = min(size, MaxPageLimit)
+	return func(db DB) DB {
+		return db.Offset((page - 1) * size).Limit(size)
+	}
+}
@@ -0,0 +1,43 @@
+package db_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pundiai/go-sdk/db"
+	"github.com/pundiai/go-sdk/log"
+	"github.com/pundiai/go-sdk/model"
+)
+
+func TestScopes(t *testing.T) {
+	testDB := db.NewMemoryDB(log.LevelError, "scopes")
+	require.NoError(t, testDB.AutoMigrate(&pageModel{}))
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	// ids 1..5 are created at 1..5 seconds after start
+	for id := uint(1); id <= 5; id++ {
+		createdAt := start.Add(time.Duration(id) * time.Second)
+		require.NoError(t, testDB.Create(&pageModel{Base: model.Base{ID: id, CreatedAt: createdAt}}))
+	}
+
+	find := func(funcs ...func(db.DB) db.DB) []uint {
+		var models []pageModel
+		require.NoError(t, testDB.Scopes(funcs...).Find(&models))
+		return pageIDs(models)
+	}
+	assert.Equal(t, []uint{2, 4}, find(db.ByIDs(2, 4)))
+	assert.Empty(t, find(db.ByIDs()))
+	assert.Equal(t, []uint{3, 5}, find(db.WhereIn("id", []int64{3, 5, 7})))
+	assert.Equal(t, []uint{2, 3}, find(db.CreatedBetween(start.Add(2*time.Second), start.Add(4*time.Second))))
+	assert.Equal(t, []uint{4, 5}, find(db.CreatedBetween(start.Add(4*time.Second), time.Time{})))
+	assert.Equal(t, []uint{5, 4, 3, 2, 1}, find(db.OrderByDesc("id")))
+	assert.Equal(t, []uint{3, 2}, find(db.OrderByDesc("created_at"), db.Paginate(2, 2)))
+	assert.Equal(t, []uint{1}, find(db.OrderByDesc("id"), db.Paginate(3, 2)))
+	assert.Equal(t, []uint{1, 2, 3, 4, 5}, find(db.Paginate(0, 0)))
+
+	var count int64
+	testDB.Model(&pageModel{}).Scopes(db.ByIDs(1, 2, 3), db.CreatedBetween(start.Add(2*time.Second), time.Time{})).Count(&count)
+	assert.EqualValues(t, 2, count)
+}
@@ -0,0 +1,224 @@
+package db
+
+import (
+	"context"
+	sqldriver "database/sql/driver"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// passwordFilePrefix marks a password read from a file, e.g.
+// root:file:///run/secrets/db_password@tcp(127.0.0.1:3306)/my
+const passwordFilePrefix = "file://"
+
+var postgresKeywordPattern = regexp.MustCompile(`(^|\s)(user|password|host|dbname)=`)
+
+// ResolveSource returns Source with environment variables expanded, User and
+// PasswordFile applied and the file:// password reference read from disk.
+func (c Config) ResolveSource() (string, error) {
+	driver, err := GetDriver(c.Driver)
+	if err != nil {
+		return "", errors.WithMessage(err, "get driver error")
+	}
+	return c.resolveSource(driver, c.Source)
+}
+
+// resolveSource applies the credentials of the config to any of its sources,
+// the password file is read on every call so that a rotated password is used.
+func (c Config) resolveSource(driver Driver, source string) (string, error) {
+	return c.applyCredentials(driver, source, readPasswordFile)
+}
+
+// namingSource applies the credentials leaving the password file unread, which
+// is enough to name the database
+func (c Config) namingSource(driver Driver, source string) (string, error) {
+	return c.applyCredentials(driver, source, func(name string) (string, error) {
+		return passwordFilePrefix + name, nil
+	})
+}
+
+func (c Config) applyCredentials(driver Driver, source string, readPassword func(name string) (string, error)) (string, error) {
+	source = os.ExpandEnv(source)
+	if c.User == "" && c.PasswordFile == "" && !strings.Contains(source, passwordFilePrefix) {
+		return source, nil
+	}
+	credentials, ok := driver.(CredentialsDriver)
+	if !ok {
+		return "", errors.New("driver does not support credentials")
+	}
+	user, password, err := credentials.Credentials(source)
+	if err != nil {
+		return "", err
+	}
+	if c.User != "" {
+		user = c.User
+	}
+	if c.PasswordFile != "" {
+		password = passwordFilePrefix + c.PasswordFile
+	}
+	if strings.HasPrefix(password, passwordFilePrefix) {
+		if password, err = readPassword(strings.TrimPrefix(password, passwordFilePrefix)); err != nil {
+			return "", err
+		}
+	}
+	return credentials.WithCredentials(source, user, password)
+}
+
+func (c Config) hasPasswordFile(driver Driver, source string) bool {
+	credentials, ok := driver.(CredentialsDriver)
+	if !ok {
+		return false
+	}
+	if c.PasswordFile != "" {
+		return true
+	}
+	_, password, err := credentials.Credentials(os.ExpandEnv(source))
+	return err == nil && strings.HasPrefix(password, passwordFilePrefix)
+}
+
+func readPasswordFile(name string) (string, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return "", errors.Wrap(err, "read password file error")
+	}
+	password := strings.TrimRight(string(data), "\r\n")
+	if password == "" {
+		return "", errors.Errorf("password file %s is empty", name)
+	}
+	return password, nil
+}
+
+// openSource opens a source with a password file through a connector that
+// reads the file again on every new connection.
+func openSource(driver Driver, config Config, source string) (gorm.Dialector, error) {
+	if config.hasPasswordFile(driver, source) {
+		return driver.(CredentialsDriver).OpenResolved(func() (string, error) {
+			return config.connectSource(driver, source)
+		}), nil
+	}
+	resolved, err := config.connectSource(driver, source)
+	if err != nil {
+		return nil, err
+	}
+	return driver.Open(resolved), nil
+}
+
+// connectSource resolves source and adds the connection options of the driver,
+// which are left out of the source used to name or migrate the database
+func (c Config) connectSource(driver Driver, source string) (string, error) {
+	source, err := c.resolveSource(driver, source)
+	if err != nil {
+		return "", err
+	}
+	if connect, ok := driver.(ConnectDriver); ok {
+		source = connect.ConnectSource(source, c)
+	}
+	return source, nil
+}
+
+func openSources(driver Driver, config Config, sources []string) ([]gorm.Dialector, error) {
+	dialectors := make([]gorm.Dialector, 0, len(sources))
+	for _, source := range sources {
+		dialector, err := openSource(driver, config, source)
+		if err != nil {
+			return nil, err
+		}
+		dialectors = append(dialectors, dialector)
+	}
+	return dialectors, nil
+}
+
+var _ sqldriver.Connector = (*sourceConnector)(nil)
+
+type sourceConnector struct {
+	driver  sqldriver.Driver
+	resolve func() (string, error)
+}
+
+func newSourceConnector(driver sqldriver.Driver, resolve func() (string, error)) *sourceConnector {
+	return &sourceConnector{driver: driver, resolve: resolve}
+}
+
+func (c *sourceConnector) Connect(ctx context.Context) (sqldriver.Conn, error) {
+	source, err := c.resolve()
+	if err != nil {
+		return nil, err
+	}
+	driverCtx, ok := c.driver.(sqldriver.DriverContext)
+	if !ok {
+		return c.driver.Open(source)
+	}
+	connector, err := driverCtx.OpenConnector(source)
+	if err != nil {
+		return nil, err
+	}
+	return connector.Connect(ctx)
+}
+
+func (c *sourceConnector) Driver() sqldriver.Driver {
+	return c.driver
+}
+
+// SourceDesensitization masks the user and password of a mysql or postgres
+// source, guessing the driver from its format.
+func SourceDesensitization(source string) string {
+	var driver Driver = &Mysql{}
+	if isPostgresURL(source) || postgresKeywordPattern.MatchString(source) {
+		driver = &Postgres{}
+	}
+	return maskSource(driver, source)
+}
+
+// sourceDesensitization masks source with the parser of the configured driver,
+// falling back to SourceDesensitization when it finds no credentials.
+func (c Config) sourceDesensitization(source string) string {
+	driver, err := GetDriver(c.Driver)
+	if err != nil {
+		return SourceDesensitization(source)
+	}
+	if masked := maskSource(driver, source); masked != source {
+		return masked
+	}
+	return SourceDesensitization(source)
+}
+
+func (c Config) sourcesDesensitization(sources []string) []string {
+	if sources == nil {
+		return nil
+	}
+	result := make([]string, 0, len(sources))
+	for _, source := range sources {
+		result = append(result, c.sourceDesensitization(source))
+	}
+	return result
+}
+
+// maskSource replaces each character of the credentials with '*', a source the
+// driver cannot parse is masked entirely rather than leaked.
+func maskSource(driver Driver, source string) string {
+	credentials, ok := driver.(CredentialsDriver)
+	if !ok {
+		return source
+	}
+	user, password, err := credentials.Credentials(source)
+	if err != nil {
+		return strings.Repeat("*", len(source))
+	}
+	if user == "" && password == "" {
+		return source
+	}
+	maskedUser, maskedPassword := "*", "*"
+	if password != "" {
+		maskedUser, maskedPassword = strings.Repeat("*", len(user)), strings.Repeat("*", len(password))
+	}
+	masked, err := credentials.WithCredentials(source, maskedUser, maskedPassword)
+	if err != nil {
+		return strings.Repeat("*", len(source))
+	}
+	// url sources escape the '*' of the masked user info
+	return strings.ReplaceAll(masked, "%2A", "*")
+}
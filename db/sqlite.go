@@ -2,17 +2,23 @@ package db
 
 import (
 	"database/sql"
+	"net/url"
 	"os"
 	"path/filepath"
 	"regexp"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/golang-migrate/migrate/v4/database"
 	migratesqlite "github.com/golang-migrate/migrate/v4/database/sqlite3"
 	"github.com/golang-migrate/migrate/v4/source"
+	"github.com/mattn/go-sqlite3"
 	"github.com/pkg/errors"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 
 	"github.com/pundiai/go-sdk/log"
 )
@@ -23,54 +29,202 @@ func init() {
 	RegisterDriver(SqliteDriver, &Sqlite{})
 }
 
-var _ Driver = (*Sqlite)(nil)
+var (
+	sqliteFileNamePattern = regexp.MustCompile(`file:([^\.]+)\.db`)
+	sqliteJournalModes    = []string{"DELETE", "TRUNCATE", "PERSIST", "MEMORY", "WAL", "OFF"}
+	sqliteSynchronous     = []string{"OFF", "NORMAL", "FULL", "EXTRA"}
+)
+
+// SqliteConfig holds the pragmas set on every new connection, an empty value
+// keeps the sqlite default
+type SqliteConfig struct {
+	// JournalMode is one of DELETE, TRUNCATE, PERSIST, MEMORY, WAL and OFF,
+	// WAL lets readers run alongside a writer
+	JournalMode string `yaml:"journal_mode" mapstructure:"journal_mode"`
+	// BusyTimeout is how long a statement waits for a lock held by another connection
+	BusyTimeout time.Duration `yaml:"busy_timeout" mapstructure:"busy_timeout"`
+	// Synchronous is one of OFF, NORMAL, FULL and EXTRA, NORMAL is safe with WAL
+	Synchronous string `yaml:"synchronous" mapstructure:"synchronous"`
+	ForeignKeys bool   `yaml:"foreign_keys" mapstructure:"foreign_keys"`
+}
+
+func (c SqliteConfig) validate() error {
+	if c.JournalMode != "" && !slices.Contains(sqliteJournalModes, strings.ToUpper(c.JournalMode)) {
+		return errors.Errorf("journal_mode must be one of %s", strings.Join(sqliteJournalModes, ", "))
+	}
+	if c.BusyTimeout < 0 {
+		return errors.New("busy_timeout must not be negative")
+	}
+	if c.Synchronous != "" && !slices.Contains(sqliteSynchronous, strings.ToUpper(c.Synchronous)) {
+		return errors.Errorf("synchronous must be one of %s", strings.Join(sqliteSynchronous, ", "))
+	}
+	return nil
+}
+
+// ConnectSource adds the pragmas of config.Sqlite to source
+func (*Sqlite) ConnectSource(source string, config Config) string {
+	return config.Sqlite.withPragmas(source)
+}
+
+// withPragmas adds the pragmas to the query of source as go-sqlite3 parameters,
+// the ones already set by source under any of their names are kept.
+func (c SqliteConfig) withPragmas(source string) string {
+	var query string
+	if i := strings.Index(source, "?"); i >= 0 {
+		query = source[i+1:]
+	}
+	set, err := url.ParseQuery(query)
+	if err != nil {
+		// go-sqlite3 rejects the source when connecting
+		return source
+	}
+	params := make(url.Values)
+	add := func(value string, keys ...string) {
+		if value == "" || slices.ContainsFunc(keys, set.Has) {
+			return
+		}
+		params.Set(keys[0], value)
+	}
+	add(strings.ToUpper(c.JournalMode), "_journal_mode", "_journal")
+	if c.BusyTimeout > 0 {
+		add(strconv.FormatInt(c.BusyTimeout.Milliseconds(), 10), "_busy_timeout", "_timeout")
+	}
+	add(strings.ToUpper(c.Synchronous), "_synchronous", "_sync")
+	if c.ForeignKeys {
+		add("1", "_foreign_keys", "_fk")
+	}
+	if len(params) == 0 {
+		return source
+	}
+	separator := "?"
+	if strings.Contains(source, "?") {
+		separator = "&"
+	}
+	return source + separator + params.Encode()
+}
 
-type Sqlite struct {
-	name string
+// sqliteFile returns the path of the database file of source, ok is false for
+// an in-memory database which has no file
+func sqliteFile(source string) (name string, ok bool) {
+	source = os.ExpandEnv(source)
+	name, query, _ := strings.Cut(strings.TrimPrefix(source, "file:"), "?")
+	if name == "" || name == ":memory:" {
+		return "", false
+	}
+	// go-sqlite3 passes the query to sqlite for file: uris only
+	if strings.HasPrefix(source, "file:") {
+		if params, err := url.ParseQuery(query); err == nil && params.Get("mode") == "memory" {
+			return "", false
+		}
+	}
+	return name, true
 }
 
+var (
+	_ Driver            = (*Sqlite)(nil)
+	_ CredentialsDriver = (*Sqlite)(nil)
+	_ UpsertDriver      = (*Sqlite)(nil)
+	_ PlaceholderDriver = (*Sqlite)(nil)
+	_ LockingDriver     = (*Sqlite)(nil)
+	_ ErrorClassifier   = (*Sqlite)(nil)
+	_ DryRunDriver      = (*Sqlite)(nil)
+	_ ConnectDriver     = (*Sqlite)(nil)
+)
+
+type Sqlite struct{}
+
 func (*Sqlite) Open(source string) gorm.Dialector {
-	if err := os.MkdirAll(filepath.Dir(source), os.ModePerm); err != nil {
-		panic(err)
+	if name, ok := sqliteFile(source); ok {
+		if err := os.MkdirAll(filepath.Dir(name), os.ModePerm); err != nil {
+			panic(err)
+		}
 	}
 	return sqlite.Open(source)
 }
 
-func (s *Sqlite) ParseSource(source string) error {
+func (*Sqlite) OpenResolved(resolve func() (string, error)) gorm.Dialector {
+	return sqlite.New(sqlite.Config{Conn: sql.OpenDB(newSourceConnector(&sqlite3.SQLiteDriver{}, resolve))})
+}
+
+// DryRunDialector opens an in-memory database, sqlite reads its version on initialization
+func (*Sqlite) DryRunDialector() gorm.Dialector {
+	return sqlite.Open("file:recorder.db?mode=memory")
+}
+
+// Credentials returns nothing, sqlite sources carry no credentials
+func (*Sqlite) Credentials(string) (user, password string, err error) {
+	return "", "", nil
+}
+
+func (*Sqlite) WithCredentials(source, user, password string) (string, error) {
+	if user != "" || password != "" {
+		return "", errors.New("sqlite: user and password are not supported")
+	}
+	return source, nil
+}
+
+func (*Sqlite) ParseSource(source string) error {
+	_, err := parseSqliteSource(source)
+	return err
+}
+
+// parseSqliteSource returns the database name of source, the file name without
+// its .db suffix
+func parseSqliteSource(source string) (string, error) {
 	if source == "" {
-		return errors.New("sqlite: db name is empty")
+		return "", errors.New("sqlite: db name is empty")
 	}
-	match := regexp.MustCompile(`file:([^\.]+)\.db`).FindStringSubmatch(source)
-	if len(match) > 1 {
-		s.name = match[1]
-		return nil
+	if match := sqliteFileNamePattern.FindStringSubmatch(source); len(match) > 1 {
+		return match[1], nil
 	}
-
 	if !strings.HasSuffix(source, ".db") {
-		return errors.New("sqlite: db name suffix must be .db")
+		return "", errors.New("sqlite: db name suffix must be .db")
 	}
-	s.name = strings.TrimSuffix(filepath.Base(source), ".db")
-	return nil
+	return strings.TrimSuffix(filepath.Base(source), ".db"), nil
 }
 
-func (s *Sqlite) GetDatabaseName(source string) string {
-	if err := s.ParseSource(source); err != nil {
+func (*Sqlite) GetDatabaseName(source string) string {
+	name, err := parseSqliteSource(source)
+	if err != nil {
 		panic(err)
 	}
-	return s.name
+	return name
 }
 
+// CreateDB creates an empty database file along with its parent directory, an
+// in-memory database is left alone
 func (*Sqlite) CreateDB(logger log.Logger, config Config) error {
-	if err := os.MkdirAll(config.Source, os.ModePerm); err != nil {
+	name, ok := sqliteFile(config.Source)
+	if !ok {
+		logger.Info("sqlite: in-memory db has no file to create", "source", config.Source)
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(name), os.ModePerm); err != nil {
+		return errors.Wrap(err, "sqlite: create db error")
+	}
+	file, err := os.OpenFile(name, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return errors.Wrap(err, "sqlite: create db error")
+	}
+	if err = file.Close(); err != nil {
 		return errors.Wrap(err, "sqlite: create db error")
 	}
 	logger.Info("sqlite: create db success", "source", config.Source)
 	return nil
 }
 
+// DropDB removes the database file and its -wal and -shm companions, an
+// in-memory database is left alone
 func (*Sqlite) DropDB(logger log.Logger, config Config) error {
-	if err := os.RemoveAll(config.Source); err != nil {
-		return errors.Wrap(err, "sqlite: drop db error")
+	name, ok := sqliteFile(config.Source)
+	if !ok {
+		logger.Info("sqlite: in-memory db has no file to drop", "source", config.Source)
+		return nil
+	}
+	for _, file := range []string{name, name + "-wal", name + "-shm"} {
+		if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+			return errors.Wrap(err, "sqlite: drop db error")
+		}
 	}
 	logger.Info("sqlite: drop db success", "source", config.Source)
 	return nil
@@ -84,6 +238,44 @@ func (*Sqlite) GetMigrationsDriver() (source.Driver, error) {
 	return GetMigrationsDriver(SqliteDriver)
 }
 
+func (*Sqlite) UpsertRowsAffected(rows int64) (minimum, maximum int64) {
+	return rows, rows
+}
+
+// MaxPlaceholders is SQLITE_MAX_VARIABLE_NUMBER, which defaults to 32766 since sqlite 3.32.0
+func (*Sqlite) MaxPlaceholders() int {
+	return 32766
+}
+
+func (*Sqlite) ForUpdateClause(_, _ bool) clause.Expression {
+	return nil
+}
+
+// IsRetryableError reports SQLITE_BUSY and SQLITE_LOCKED, raised when another
+// connection holds a conflicting lock
+func (*Sqlite) IsRetryableError(err error) bool {
+	var sqliteErr sqlite3.Error
+	if !errors.As(err, &sqliteErr) {
+		return false
+	}
+	return sqliteErr.Code == sqlite3.ErrBusy || sqliteErr.Code == sqlite3.ErrLocked
+}
+
+func (*Sqlite) ClassifyError(err error) error {
+	var sqliteErr sqlite3.Error
+	if !errors.As(err, &sqliteErr) {
+		return nil
+	}
+	switch sqliteErr.ExtendedCode {
+	case sqlite3.ErrConstraintUnique, sqlite3.ErrConstraintPrimaryKey:
+		return ErrDuplicateKey
+	case sqlite3.ErrConstraintForeignKey:
+		return ErrForeignKey
+	default:
+		return nil
+	}
+}
+
 func (*Sqlite) ToMigrateDriver(source string) (string, database.Driver, error) {
 	db, err := sql.Open("sqlite3", source)
 	if err != nil {
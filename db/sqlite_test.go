@@ -1,9 +1,11 @@
 package db_test
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
@@ -75,16 +77,108 @@ func (suite *SqliteTestSuite) TestGetDatabaseName() {
 }
 
 func (suite *SqliteTestSuite) TestCreateDB() {
-	source := suite.T().TempDir() + "/my.db"
+	source := suite.T().TempDir() + "/data/my.db"
 	suite.Require().NoError(suite.driver.CreateDB(log.NewNopLogger(), db.Config{Source: source}))
-	defer func() {
-		suite.Require().NoError(suite.driver.DropDB(log.NewNopLogger(), db.Config{Source: source}))
-	}()
 	stat, err := os.Stat(source)
 	suite.Require().NoError(err)
-	suite.Require().NotNil(stat)
-	suite.True(stat.IsDir())
+	suite.False(stat.IsDir())
+	suite.Zero(stat.Size())
 	suite.Equal("my.db", stat.Name())
+
+	config := db.NewDefConfig()
+	config.Source = source
+	config.EnableMetric = false
+	config.Sqlite.JournalMode = "wal"
+	testDB, err := db.NewDB(context.Background(), log.NewNopLogger(), config)
+	suite.Require().NoError(err)
+	suite.Require().NoError(testDB.Exec("CREATE TABLE t (id INTEGER PRIMARY KEY)"))
+	suite.FileExists(source + "-wal")
+	suite.FileExists(source + "-shm")
+
+	suite.Require().NoError(suite.driver.DropDB(log.NewNopLogger(), config))
+	suite.Require().NoError(testDB.Close())
+	suite.NoFileExists(source)
+	suite.NoFileExists(source + "-wal")
+	suite.NoFileExists(source + "-shm")
+	suite.Require().NoError(suite.driver.DropDB(log.NewNopLogger(), config))
+}
+
+func (suite *SqliteTestSuite) TestMemoryDBFiles() {
+	wd, err := os.Getwd()
+	suite.Require().NoError(err)
+	suite.Require().NoError(os.Chdir(suite.T().TempDir()))
+	defer func() { suite.Require().NoError(os.Chdir(wd)) }()
+
+	suite.Require().NoError(os.WriteFile("x.db", nil, 0o600))
+	for _, source := range []string{":memory:", "file::memory:?cache=shared", "file:x.db?mode=memory&cache=shared"} {
+		config := db.Config{Source: source}
+		suite.Require().NoError(suite.driver.CreateDB(log.NewNopLogger(), config))
+		suite.Require().NoError(suite.driver.DropDB(log.NewNopLogger(), config))
+	}
+	suite.FileExists("x.db")
+	suite.NotNil(suite.driver.Open("file:data/x.db?mode=memory"))
+	suite.NoDirExists("file:data")
+	suite.NoDirExists("data")
+	suite.NotNil(suite.driver.Open("file:data/x.db?cache=shared"))
+	suite.DirExists("data")
+	suite.Require().NoError(os.Remove("data"))
+
+	testDB := db.NewMemoryDB(log.LevelError, "x")
+	defer testDB.Close()
+	suite.Require().NoError(testDB.Ping(context.Background()))
+	entries, err := os.ReadDir(".")
+	suite.Require().NoError(err)
+	suite.Len(entries, 1)
+}
+
+func (suite *SqliteTestSuite) TestPragmas() {
+	config := db.NewDefConfig()
+	config.Source = suite.T().TempDir() + "/pragmas.db"
+	config.EnableMetric = false
+	config.Sqlite = db.SqliteConfig{
+		JournalMode: "WAL",
+		BusyTimeout: 3 * time.Second,
+		Synchronous: "normal",
+		ForeignKeys: true,
+	}
+	suite.Require().NoError(config.Validate())
+	testDB, err := db.NewDB(context.Background(), log.NewNopLogger(), config)
+	suite.Require().NoError(err)
+	defer testDB.Close()
+
+	pragma := func(name string) (value string) {
+		suite.Require().NoError(testDB.Raw("PRAGMA " + name).Scan(&value))
+		return value
+	}
+	suite.Equal("wal", pragma("journal_mode"))
+	suite.Equal("3000", pragma("busy_timeout"))
+	suite.Equal("1", pragma("synchronous"))
+	suite.Equal("1", pragma("foreign_keys"))
+	suite.Equal("pragmas", config.GetDatabaseName())
+
+	config.Sqlite.JournalMode = "fast"
+	suite.EqualError(config.Validate(), "sqlite is invalid: journal_mode must be one of DELETE, TRUNCATE, PERSIST, MEMORY, WAL, OFF")
+	config.Sqlite.JournalMode = ""
+	config.Sqlite.Synchronous = "always"
+	suite.EqualError(config.Validate(), "sqlite is invalid: synchronous must be one of OFF, NORMAL, FULL, EXTRA")
+}
+
+func (suite *SqliteTestSuite) TestConnectSource() {
+	config := db.NewDefConfig()
+	config.Sqlite = db.SqliteConfig{
+		JournalMode: "wal",
+		BusyTimeout: 3 * time.Second,
+		Synchronous: "normal",
+		ForeignKeys: true,
+	}
+	suite.Equal("my.db?_busy_timeout=3000&_foreign_keys=1&_journal_mode=WAL&_synchronous=NORMAL",
+		suite.driver.ConnectSource("my.db", config))
+	// the aliases of go-sqlite3 keep their value instead of being set twice
+	suite.Equal("file:my.db?_journal=DELETE&_timeout=100&_sync=FULL&_fk=0",
+		suite.driver.ConnectSource("file:my.db?_journal=DELETE&_timeout=100&_sync=FULL&_fk=0", config))
+	suite.Equal("file:my.db?mode=memory&_txlock=immediate&_fk=0&_busy_timeout=3000&_journal_mode=WAL&_synchronous=NORMAL",
+		suite.driver.ConnectSource("file:my.db?mode=memory&_txlock=immediate&_fk=0", config))
+	suite.Equal("my.db", suite.driver.ConnectSource("my.db", db.Config{}))
 }
 
 func TestSqliteTestSuite(t *testing.T) {
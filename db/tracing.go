@@ -0,0 +1,102 @@
+package db
+
+import (
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+const (
+	tracingPluginName = "sdk:tracing"
+	tracingSpanKey    = "sdk:tracing:span"
+
+	tracerName = "github.com/pundiai/go-sdk/db"
+)
+
+var _ gorm.Plugin = (*tracingPlugin)(nil)
+
+// tracingPlugin opens a client span for every statement, as a child of the span
+// carried by the context given to DB.WithContext. Spans are created by the global
+// tracer provider, see otel.SetTracerProvider.
+type tracingPlugin struct {
+	attrs []attribute.KeyValue
+}
+
+func newTracingPlugin(driver, database string) *tracingPlugin {
+	system := driver
+	if driver == PostgresDriver {
+		system = "postgresql"
+	}
+	return &tracingPlugin{
+		attrs: []attribute.KeyValue{
+			attribute.String("db.system", system),
+			attribute.String("db.name", database),
+		},
+	}
+}
+
+func (*tracingPlugin) Name() string {
+	return tracingPluginName
+}
+
+func (p *tracingPlugin) Initialize(db *gorm.DB) error {
+	callback := db.Callback()
+	errs := []error{
+		callback.Create().Before("gorm:create").Register(tracingPluginName+":before_create", p.before("create")),
+		callback.Create().After("gorm:create").Register(tracingPluginName+":after_create", p.after),
+		callback.Query().Before("gorm:query").Register(tracingPluginName+":before_query", p.before("query")),
+		callback.Query().After("gorm:query").Register(tracingPluginName+":after_query", p.after),
+		callback.Update().Before("gorm:update").Register(tracingPluginName+":before_update", p.before("update")),
+		callback.Update().After("gorm:update").Register(tracingPluginName+":after_update", p.after),
+		callback.Delete().Before("gorm:delete").Register(tracingPluginName+":before_delete", p.before("delete")),
+		callback.Delete().After("gorm:delete").Register(tracingPluginName+":after_delete", p.after),
+		callback.Raw().Before("gorm:raw").Register(tracingPluginName+":before_raw", p.before("raw")),
+		callback.Raw().After("gorm:raw").Register(tracingPluginName+":after_raw", p.after),
+		callback.Row().Before("gorm:row").Register(tracingPluginName+":before_row", p.before("row")),
+		callback.Row().After("gorm:row").Register(tracingPluginName+":after_row", p.after),
+	}
+	for _, err := range errs {
+		if err != nil {
+			return errors.Wrap(err, "register tracing callback error")
+		}
+	}
+	return nil
+}
+
+func (p *tracingPlugin) before(operation string) func(db *gorm.DB) {
+	return func(db *gorm.DB) {
+		// the statement context is left untouched, a chained gorm.DB shares its
+		// statement and would otherwise parent later spans under this one
+		_, span := otel.Tracer(tracerName).Start(db.Statement.Context, "db."+operation,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(p.attrs...),
+			trace.WithAttributes(attribute.String("db.operation", operation)),
+		)
+		db.InstanceSet(tracingSpanKey, span)
+	}
+}
+
+func (*tracingPlugin) after(db *gorm.DB) {
+	value, ok := db.InstanceGet(tracingSpanKey)
+	if !ok {
+		return
+	}
+	span, ok := value.(trace.Span)
+	if !ok {
+		return
+	}
+	defer span.End()
+
+	span.SetAttributes(
+		attribute.String("db.sql.table", db.Statement.Table),
+		attribute.String("db.statement", db.Statement.SQL.String()),
+		attribute.Int64("db.rows_affected", db.Statement.RowsAffected),
+	)
+	if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) {
+		span.RecordError(db.Error)
+		span.SetStatus(codes.Error, db.Error.Error())
+	}
+}
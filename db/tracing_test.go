@@ -0,0 +1,62 @@
+package db_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+
+	"github.com/pundiai/go-sdk/db"
+	"github.com/pundiai/go-sdk/log"
+)
+
+func TestTracing(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	defer func(previous trace.TracerProvider) { otel.SetTracerProvider(previous) }(otel.GetTracerProvider())
+	otel.SetTracerProvider(provider)
+
+	config := db.NewDefConfig()
+	config.Source = "file:tracing.db?mode=memory"
+	config.EnableMetric = false
+	config.EnableTracing = true
+	testDB, err := db.NewDB(context.Background(), log.NewNopLogger(), config)
+	require.NoError(t, err)
+	defer testDB.Close()
+	require.NoError(t, testDB.AutoMigrate(&gorm.Model{}))
+	exporter.Reset()
+
+	ctx, parent := provider.Tracer("test").Start(context.Background(), "request")
+	require.NoError(t, testDB.WithContext(ctx).Create(&gorm.Model{ID: 1}))
+	require.Error(t, testDB.WithContext(ctx).Create(&gorm.Model{ID: 1}))
+	parent.End()
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 3)
+	for _, span := range spans[:2] {
+		assert.Equal(t, "db.create", span.Name)
+		assert.Equal(t, trace.SpanKindClient, span.SpanKind)
+		assert.Equal(t, parent.SpanContext().SpanID(), span.Parent.SpanID())
+		attrs := attribute.NewSet(span.Attributes...)
+		system, _ := attrs.Value("db.system")
+		assert.Equal(t, "sqlite", system.AsString())
+		name, _ := attrs.Value("db.name")
+		assert.Equal(t, "tracing", name.AsString())
+		statement, _ := attrs.Value("db.statement")
+		assert.Contains(t, statement.AsString(), "INSERT INTO `model`")
+	}
+	attrs := attribute.NewSet(spans[0].Attributes...)
+	rows, _ := attrs.Value("db.rows_affected")
+	assert.Equal(t, int64(1), rows.AsInt64())
+	assert.Equal(t, codes.Unset, spans[0].Status.Code)
+	assert.Equal(t, codes.Error, spans[1].Status.Code)
+	assert.Equal(t, "request", spans[2].Name)
+}
@@ -2,7 +2,6 @@ package migration
 
 import (
 	"context"
-	"os"
 
 	"github.com/golang-migrate/migrate/v4"
 	"github.com/pkg/errors"
@@ -44,7 +43,11 @@ func (s *Server) Start(context.Context, *errgroup.Group) error {
 	if err != nil {
 		return errors.Wrap(err, "migration source driver error")
 	}
-	databaseName, dbDriver, err := driver.ToMigrateDriver(os.ExpandEnv(s.db.GetSource()))
+	source, err := s.db.ResolveSource()
+	if err != nil {
+		return err
+	}
+	databaseName, dbDriver, err := driver.ToMigrateDriver(source)
 	if err != nil {
 		return errors.WithMessage(err, "to migrate driver error")
 	}
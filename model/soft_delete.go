@@ -0,0 +1,16 @@
+package model
+
+import (
+	"gorm.io/gorm"
+)
+
+// SoftDeleteBase is Base with a deleted-at column, deleting a model embedding it
+// only sets DeletedAt and queries skip the deleted rows unless unscoped.
+type SoftDeleteBase struct {
+	Base      `gorm:"embedded"`
+	DeletedAt gorm.DeletedAt `json:"deleted_at" gorm:"index;comment:delete time"`
+}
+
+func (b *SoftDeleteBase) IsDeleted() bool {
+	return b.DeletedAt.Valid
+}
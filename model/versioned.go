@@ -0,0 +1,24 @@
+package model
+
+import (
+	"gorm.io/gorm"
+)
+
+// Versioned adds an optimistic lock version, an update through db.DB only
+// matches the row at the version of the updated model and increments it.
+type Versioned struct {
+	Version uint64 `json:"version" gorm:"not null;default:1;comment:optimistic lock version"`
+}
+
+func (v *Versioned) GetVersion() uint64 {
+	return v.Version
+}
+
+// BeforeCreate starts the version at 1, a model defining its own BeforeCreate
+// hook has to call it.
+func (v *Versioned) BeforeCreate(*gorm.DB) error {
+	if v.Version == 0 {
+		v.Version = 1
+	}
+	return nil
+}
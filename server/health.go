@@ -0,0 +1,75 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+)
+
+type HealthStatus string
+
+const (
+	HealthStatusHealthy   HealthStatus = "healthy"
+	HealthStatusDegraded  HealthStatus = "degraded"
+	HealthStatusUnhealthy HealthStatus = "unhealthy"
+)
+
+func (s HealthStatus) severity() int {
+	switch s {
+	case HealthStatusHealthy:
+		return 0
+	case HealthStatusDegraded:
+		return 1
+	default:
+		return 2
+	}
+}
+
+type HealthCheck struct {
+	Status  HealthStatus   `json:"status"`
+	Message string         `json:"message,omitempty"`
+	Details map[string]any `json:"details,omitempty"`
+}
+
+type HealthChecker interface {
+	// Name identifies the checked component in the health report
+	Name() string
+	// CheckHealth reports the component status, it should return before ctx is done
+	CheckHealth(ctx context.Context) HealthCheck
+}
+
+type HealthReport struct {
+	Status HealthStatus           `json:"status"`
+	Checks map[string]HealthCheck `json:"checks"`
+}
+
+// CheckHealth runs all checkers, the report status is the worst of them.
+func CheckHealth(ctx context.Context, checkers ...HealthChecker) HealthReport {
+	report := HealthReport{
+		Status: HealthStatusHealthy,
+		Checks: make(map[string]HealthCheck, len(checkers)),
+	}
+	for _, checker := range checkers {
+		check := checker.CheckHealth(ctx)
+		report.Checks[checker.Name()] = check
+		if check.Status.severity() > report.Status.severity() {
+			report.Status = check.Status
+		}
+	}
+	return report
+}
+
+// NewHealthHandler serves the health report as json, it responds 503 when any
+// checker is unhealthy, a degraded service still responds 200.
+func NewHealthHandler(checkers ...HealthChecker) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		report := CheckHealth(r.Context(), checkers...)
+		w.Header().Set("Content-Type", "application/json")
+		if report.Status == HealthStatusUnhealthy {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		} else {
+			w.WriteHeader(http.StatusOK)
+		}
+		_ = json.NewEncoder(w).Encode(report)
+	})
+}
@@ -5,6 +5,7 @@ import (
 	"os"
 
 	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
 )
 
 func LoadJSONFile(path string, data any) error {
@@ -20,6 +21,24 @@ func LoadJSONFile(path string, data any) error {
 	return nil
 }
 
+// LoadYAMLFile decodes a YAML file into data through its json tags, so that the
+// same struct loads both YAML and JSON files.
+func LoadYAMLFile(path string, data any) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var value any
+	if err = yaml.Unmarshal(content, &value); err != nil {
+		return err
+	}
+	bts, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(bts, data)
+}
+
 func PathExists(path string) (bool, error) {
 	_, err := os.Stat(path)
 	if err == nil {